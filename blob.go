@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/urfave/cli/v2"
+)
+
+// blobFlags returns the flags common to the blob commands.
+func blobFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "space",
+			Value: "",
+			Usage: "Name or DID of space the blobs are stored in. Defaults to the configured space, or else the current space.",
+		},
+		&cli.StringFlag{
+			Name:  "proof",
+			Value: "",
+			Usage: "Path to file containing UCAN proof(s) for the operation.",
+		},
+	}
+}
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:  "blob",
+		Usage: "Manage the blobs stored in a space.",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List blobs in the space.",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "cursor",
+						Value: "",
+						Usage: "Cursor of the page to list, as printed after the previous page.",
+					},
+					&cli.Uint64Flag{
+						Name:  "size",
+						Value: 0,
+						Usage: "Maximum number of blobs to list. The service's default if unset.",
+					},
+				}, blobFlags()...),
+				Action: blobLs,
+			},
+			{
+				Name:      "get",
+				Usage:     "Show details of a blob in the space.",
+				UsageText: "blob get <digest>",
+				Flags:     blobFlags(),
+				Action:    blobGet,
+			},
+			{
+				Name:      "rm",
+				Aliases:   []string{"remove"},
+				Usage:     "Remove a blob from the space.",
+				UsageText: "blob rm <digest>",
+				Flags:     blobFlags(),
+				Action:    blobRm,
+			},
+		},
+	})
+}
+
+func blobLs(cCtx *cli.Context) error {
+	proofs := []delegation.Delegation{}
+	if cCtx.String("proof") != "" {
+		proof := cmdutil.MustGetProof(cCtx.String("proof"))
+		proofs = append(proofs, proof)
+	}
+
+	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	params := spaceblobcap.ListCaveats{}
+	if cursor := cCtx.String("cursor"); cursor != "" {
+		params.Cursor = &cursor
+	}
+	if size := cCtx.Uint64("size"); size != 0 {
+		params.Size = &size
+	}
+
+	listOk, err := c.SpaceBlobList(cCtx.Context, space, params)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range listOk.Results {
+		fmt.Printf("%s\t%d\n", r.Blob.Digest.B58String(), r.Blob.Size)
+	}
+
+	if listOk.Cursor != nil && *listOk.Cursor != "" && len(listOk.Results) > 0 {
+		fmt.Fprintf(os.Stderr, "Next page: --cursor %s\n", *listOk.Cursor)
+	}
+
+	return nil
+}
+
+func blobGet(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("blob digest is required")
+	}
+
+	digest := cmdutil.MustParseDigest(cCtx.Args().First())
+
+	proofs := []delegation.Delegation{}
+	if cCtx.String("proof") != "" {
+		proof := cmdutil.MustGetProof(cCtx.String("proof"))
+		proofs = append(proofs, proof)
+	}
+
+	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	getOk, err := c.SpaceBlobGet(cCtx.Context, space, digest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Digest:   %s\n", getOk.Blob.Digest.B58String())
+	fmt.Printf("Size:     %d\n", getOk.Blob.Size)
+	fmt.Printf("Added:    %s\n", getOk.InsertedAt)
+	fmt.Printf("Cause:    %s\n", getOk.Cause)
+
+	return nil
+}
+
+func blobRm(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("blob digest is required")
+	}
+
+	digest := cmdutil.MustParseDigest(cCtx.Args().First())
+
+	proofs := []delegation.Delegation{}
+	if cCtx.String("proof") != "" {
+		proof := cmdutil.MustGetProof(cCtx.String("proof"))
+		proofs = append(proofs, proof)
+	}
+
+	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	removeOk, err := c.SpaceBlobRemove(cCtx.Context, space, digest)
+	if err != nil {
+		return err
+	}
+
+	if removeOk.Size == 0 {
+		fmt.Printf("Blob %s was not stored in the space\n", digest.B58String())
+		return nil
+	}
+	fmt.Printf("Removed blob %s (%d bytes)\n", digest.B58String(), removeOk.Size)
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/storacha/guppy/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	var keys string
+	for _, s := range config.Settings {
+		keys += fmt.Sprintf("\n   %-13s %s", s.Key, s.Usage)
+	}
+
+	commands = append(commands, &cli.Command{
+		Name:  "config",
+		Usage: "Manage the settings of the profile in use. Flags and environment variables take precedence over them.",
+		Description: "Settings are stored in config.yaml in the profile's directory, such as ~/.guppy/config.yaml. The settings are:" +
+			keys,
+		Subcommands: []*cli.Command{
+			{
+				Name:      "get",
+				Usage:     "Print the value of a setting, or nothing if it's unset.",
+				UsageText: "config get <key>",
+				Action:    configGet,
+			},
+			{
+				Name:      "set",
+				Usage:     "Set a setting. Setting it to \"\" unsets it.",
+				UsageText: "config set <key> <value>",
+				Action:    configSet,
+			},
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List the settings which are set.",
+				Action:  configLs,
+			},
+		},
+	})
+}
+
+func configGet(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return fmt.Errorf("config key is required")
+	}
+
+	v, err := cmdutil.MustGetProfile().Config.Get(cCtx.Args().First())
+	if err != nil {
+		return err
+	}
+	if v != "" {
+		fmt.Println(v)
+	}
+
+	return nil
+}
+
+func configSet(cCtx *cli.Context) error {
+	if cCtx.NArg() != 2 {
+		return fmt.Errorf("config key and value are required")
+	}
+	key, value := cCtx.Args().Get(0), cCtx.Args().Get(1)
+
+	p := cmdutil.MustGetProfile()
+	if err := p.Config.Set(key, value); err != nil {
+		return err
+	}
+	if err := p.Config.Save(p.ConfigPath()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func configLs(cCtx *cli.Context) error {
+	cfg := cmdutil.MustGetProfile().Config
+
+	for _, s := range config.Settings {
+		v, err := cfg.Get(s.Key)
+		if err != nil {
+			return err
+		}
+		if v != "" {
+			fmt.Printf("%s: %s\n", s.Key, v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:      "delegate",
+		Usage:     "Delegate capabilities on a space to another agent.",
+		UsageText: "delegate <audience> --can <ability> [--can <ability>...] [--space <space>] [--expiration <duration>] [-o <file>]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "can",
+				Aliases:  []string{"c"},
+				Usage:    "Ability to delegate, such as upload/add or space/blob/add. May be given more than once.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "space",
+				Value: "",
+				Usage: "Name or DID of space to delegate capabilities on. Defaults to the configured space, or else the current space.",
+			},
+			&cli.DurationFlag{
+				Name:  "expiration",
+				Usage: "How long the delegation is valid for, such as 24h. Defaults to never expiring.",
+			},
+			&cli.DurationFlag{
+				Name:  "not-before",
+				Usage: "How long until the delegation becomes valid. Defaults to immediately.",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Path to write the delegation to. Defaults to standard output.",
+			},
+			&cli.BoolFlag{
+				Name:  "base64",
+				Value: false,
+				Usage: "Write the delegation as a base64 encoded string, suitable for an environment variable, rather than as a CAR file.",
+			},
+		},
+		Action: delegate,
+	})
+}
+
+func delegate(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("audience DID is required")
+	}
+
+	audience := cmdutil.MustParseDID(cCtx.Args().First())
+
+	c := cmdutil.MustGetClient()
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	var caps []ucan.Capability[ucan.NoCaveats]
+	for _, ability := range cCtx.StringSlice("can") {
+		caps = append(caps, ucan.NewCapability(ability, space.String(), ucan.NoCaveats{}))
+	}
+
+	var opts []delegation.Option
+	if expiration := cCtx.Duration("expiration"); expiration > 0 {
+		opts = append(opts, delegation.WithExpiration(int(time.Now().Add(expiration).Unix())))
+	} else {
+		opts = append(opts, delegation.WithNoExpiration())
+	}
+	if notBefore := cCtx.Duration("not-before"); notBefore > 0 {
+		opts = append(opts, delegation.WithNotBefore(int(time.Now().Add(notBefore).Unix())))
+	}
+
+	del, err := c.Delegate(audience, caps, opts...)
+	if err != nil {
+		return fmt.Errorf("creating delegation: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if path := cCtx.String("output"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("opening output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if cCtx.Bool("base64") {
+		str, err := delegation.Format(del)
+		if err != nil {
+			return fmt.Errorf("formatting delegation: %w", err)
+		}
+		_, err = fmt.Fprintln(out, str)
+		return err
+	}
+
+	if _, err := io.Copy(out, del.Archive()); err != nil {
+		return fmt.Errorf("writing delegation: %w", err)
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -12,9 +14,11 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
 	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/ipld"
 	"github.com/storacha/go-ucanto/core/result"
 	"github.com/storacha/guppy/internal/cmdutil"
 	"github.com/storacha/guppy/internal/upload"
+	"github.com/storacha/guppy/pkg/client"
 	"github.com/storacha/guppy/pkg/didmailto"
 	"github.com/urfave/cli/v2"
 )
@@ -47,7 +51,7 @@ var commands = []*cli.Command{
 			&cli.StringFlag{
 				Name:  "space",
 				Value: "",
-				Usage: "DID of space to upload to.",
+				Usage: "Name or DID of space to upload to. Defaults to the configured space, or else the current space.",
 			},
 			&cli.StringFlag{
 				Name:  "proof",
@@ -70,7 +74,7 @@ var commands = []*cli.Command{
 				Name:    "json",
 				Aliases: []string{"j"},
 				Value:   false,
-				Usage:   "Format as newline delimited JSON",
+				Usage:   "Format as newline delimited JSON. Defaults to the configured output format.",
 			},
 			&cli.BoolFlag{
 				Name:    "verbose",
@@ -84,22 +88,30 @@ var commands = []*cli.Command{
 				Usage: "Wrap single input file in a directory. Has no effect on directory or CAR uploads. Pass --no-wrap to disable.",
 			},
 			&cli.IntFlag{
-				Name:  "shard-size",
-				Value: 0,
-				Usage: "Shard uploads into CAR files of approximately this size in bytes.",
+				Name:    "shard-size",
+				Value:   0,
+				Usage:   "Shard uploads into CAR files of approximately this size in bytes. Defaults to the configured shard size.",
+				EnvVars: []string{"GUPPY_SHARD_SIZE"},
+			},
+			&cli.IntFlag{
+				Name:    "parallel",
+				Value:   1,
+				Usage:   "Number of shards to upload at once. Defaults to the configured parallelism.",
+				EnvVars: []string{"GUPPY_PARALLEL"},
 			},
 		},
 		Action: upload.Upload,
 	},
 	{
-		Name:    "ls",
-		Aliases: []string{"list"},
-		Usage:   "List uploads in the current space.",
+		Name:      "ls",
+		Aliases:   []string{"list"},
+		Usage:     "List uploads in the current space, or show the upload with the given root.",
+		UsageText: "ls [root]",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "space",
 				Value: "",
-				Usage: "DID of space to list uploads from.",
+				Usage: "Name or DID of space to list uploads from. Defaults to the configured space, or else the current space.",
 			},
 			&cli.StringFlag{
 				Name:  "proof",
@@ -114,6 +126,35 @@ var commands = []*cli.Command{
 		},
 		Action: ls,
 	},
+	{
+		Name:      "rm",
+		Aliases:   []string{"remove"},
+		Usage:     "Remove the upload with the given root from the space. Its shards remain stored in the space unless --shards is given.",
+		UsageText: "rm <root>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "space",
+				Value: "",
+				Usage: "Name or DID of space to remove the upload from. Defaults to the configured space, or else the current space.",
+			},
+			&cli.StringFlag{
+				Name:  "proof",
+				Value: "",
+				Usage: "Path to file containing UCAN proof(s) for the operation.",
+			},
+			&cli.BoolFlag{
+				Name:  "shards",
+				Value: false,
+				Usage: "Also remove the upload's shards from the space, unless another upload references them. The upload's index is kept, as the service doesn't link it to the upload; remove it with `guppy blob rm`.",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Value: false,
+				Usage: "Print what would be removed, without removing anything.",
+			},
+		},
+		Action: rm,
+	},
 }
 
 func main() {
@@ -121,6 +162,18 @@ func main() {
 		Name:     "guppy",
 		Usage:    "interact with the Storacha Network",
 		Commands: commands,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "profile",
+				Value:   "",
+				Usage:   "Profile to use, each of which has its own agent and service settings. Defaults to the current profile.",
+				EnvVars: []string{"GUPPY_PROFILE"},
+			},
+		},
+		Before: func(cCtx *cli.Context) error {
+			cmdutil.SetProfile(cCtx.String("profile"))
+			return nil
+		},
 	}
 
 	// set up a context that is canceled when a command is interrupted
@@ -145,6 +198,12 @@ func main() {
 	}()
 
 	if err := app.RunContext(ctx, os.Args); err != nil {
+		if errors.Is(err, client.ErrUnauthorized) {
+			log.Fatalf("%s\nThe service didn't accept this agent's proofs. Try logging in again with `guppy login <email>`.", err)
+		}
+		if errors.Is(err, client.ErrNoProof) {
+			log.Fatalf("%s\nLog in with `guppy login <email>`, or pass a proof with --proof. `guppy proof ls` shows what this agent holds.", err)
+		}
 		log.Fatal(err)
 	}
 }
@@ -201,8 +260,6 @@ func reset(cCtx *cli.Context) error {
 }
 
 func ls(cCtx *cli.Context) error {
-	space := cmdutil.MustParseDID(cCtx.String("space"))
-
 	proofs := []delegation.Delegation{}
 	if cCtx.String("proof") != "" {
 		proof := cmdutil.MustGetProof(cCtx.String("proof"))
@@ -210,6 +267,22 @@ func ls(cCtx *cli.Context) error {
 	}
 
 	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	if cCtx.Args().Present() {
+		root := cmdutil.MustParseCID(cCtx.Args().First())
+
+		getOk, err := c.UploadGet(cCtx.Context, space, root)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", getOk.Root)
+		for _, s := range getOk.Shards {
+			fmt.Printf("\t%s\n", s)
+		}
+		return nil
+	}
 
 	listOk, err := c.UploadList(
 		cCtx.Context,
@@ -230,3 +303,76 @@ func ls(cCtx *cli.Context) error {
 
 	return nil
 }
+
+func rm(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("root CID is required")
+	}
+
+	root := cmdutil.MustParseCID(cCtx.Args().First())
+
+	proofs := []delegation.Delegation{}
+	if cCtx.String("proof") != "" {
+		proof := cmdutil.MustGetProof(cCtx.String("proof"))
+		proofs = append(proofs, proof)
+	}
+
+	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	if cCtx.Bool("shards") {
+		var removal client.UploadRemoval
+		var err error
+		if cCtx.Bool("dry-run") {
+			removal, err = c.PlanUploadRemoval(cCtx.Context, space, root)
+		} else {
+			removal, err = c.UploadRemoveWithShards(cCtx.Context, space, root)
+		}
+		// A removal with a root removed the upload, even if it failed to remove
+		// some of the shards, so report what it did remove before the error.
+		if err != nil && removal.Root == nil {
+			return err
+		}
+
+		verb := "Removed"
+		if cCtx.Bool("dry-run") {
+			verb = "Would remove"
+		}
+		fmt.Printf("%s upload %s\n", verb, removal.Root)
+		for _, shard := range removal.Shards {
+			switch {
+			case containsLink(removal.Remaining, shard):
+				fmt.Printf("Failed to remove shard %s\n", shard)
+			case containsLink(removal.Orphaned, shard):
+				fmt.Printf("%s shard %s\n", verb, shard)
+			default:
+				fmt.Printf("Keeping shard %s, referenced by another upload\n", shard)
+			}
+		}
+		return err
+	}
+
+	// Without --shards only the upload itself is removed, so there's no need to
+	// look at the space's other uploads.
+	if cCtx.Bool("dry-run") {
+		getOk, err := c.UploadGet(cCtx.Context, space, root)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Would remove upload %s\n", getOk.Root)
+		return nil
+	}
+
+	removeOk, err := c.UploadRemove(cCtx.Context, space, root)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed upload %s\n", removeOk.Root)
+
+	return nil
+}
+
+func containsLink(links []ipld.Link, link ipld.Link) bool {
+	return slices.ContainsFunc(links, func(l ipld.Link) bool { return l.String() == link.String() })
+}
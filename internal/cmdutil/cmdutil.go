@@ -9,7 +9,13 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
+	"sync"
 
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
 	uclient "github.com/storacha/go-ucanto/client"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/did"
@@ -17,14 +23,15 @@ import (
 	"github.com/storacha/go-ucanto/principal/ed25519/signer"
 	"github.com/storacha/go-ucanto/transport/car"
 	"github.com/storacha/go-ucanto/transport/http"
+	"github.com/storacha/guppy/internal/config"
+	"github.com/storacha/guppy/internal/profile"
 	"github.com/storacha/guppy/pkg/agentdata"
 	"github.com/storacha/guppy/pkg/client"
 	cdg "github.com/storacha/guppy/pkg/delegation"
 	receiptclient "github.com/storacha/guppy/pkg/receipt"
+	"github.com/urfave/cli/v2"
 )
 
-const defaultServiceName = "staging.up.storacha.network"
-
 // envSigner returns a principal.Signer from the environment variable
 // GUPPY_PRIVATE_KEY, if any.
 func envSigner() (principal.Signer, error) {
@@ -40,15 +47,19 @@ func envSigner() (principal.Signer, error) {
 // if any. If proofs are provided, they will be added to the client, but the
 // client will not save changes to disk to avoid storing them.
 func MustGetClient(proofs ...delegation.Delegation) *client.Client {
-	homedir, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatalf("obtaining user home directory: %s", err)
-	}
+	datapath := MustGetDataPath()
+	datadir := path.Dir(datapath)
 
-	datadir := path.Join(homedir, ".guppy")
-	datapath := path.Join(datadir, "config.json")
+	// Only ask for a passphrase once, and only if the data is encrypted. Keep
+	// saving it encrypted with the same passphrase.
+	passphrase := sync.OnceValues(GetPassphrase)
+	encrypted, _ := agentdata.IsEncrypted(datapath)
+	var fileOptions []agentdata.FileOption
+	if encrypted {
+		fileOptions = append(fileOptions, agentdata.WithPassphrase(passphrase))
+	}
 
-	data, err := agentdata.ReadFromFile(datapath)
+	data, err := agentdata.ReadFromFile(datapath, fileOptions...)
 
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
@@ -75,13 +86,26 @@ func MustGetClient(proofs ...delegation.Delegation) *client.Client {
 		clientOptions = append(clientOptions, client.WithData(data))
 	}
 
+	// The data as last read or saved, to tell which changes to it the client
+	// makes.
+	base := data.Clone()
+
 	proofsProvided := len(proofs) > 0
 
 	if !proofsProvided {
 		// Only enable saving if no proofs are provided
 		clientOptions = append(clientOptions,
-			client.WithSaveFn(func(data agentdata.AgentData) error {
-				return data.WriteToFile(datapath)
+			client.WithSaveFn(func(ours agentdata.AgentData) error {
+				// Another process may have changed the data since it was read, so apply
+				// only the changes this one made, rather than overwriting them.
+				err := agentdata.UpdateFile(datapath, func(theirs agentdata.AgentData) (agentdata.AgentData, error) {
+					return agentdata.Merge(base, ours, theirs), nil
+				}, fileOptions...)
+				if err != nil {
+					return err
+				}
+				base = ours.Clone()
+				return nil
 			}),
 		)
 	}
@@ -104,24 +128,82 @@ func MustGetClient(proofs ...delegation.Delegation) *client.Client {
 	return c
 }
 
-func MustGetConnection() uclient.Connection {
-	// service URL & DID
-	serviceURLStr := os.Getenv("STORACHA_SERVICE_URL") // use env var preferably
-	if serviceURLStr == "" {
-		serviceURLStr = fmt.Sprintf("https://%s", defaultServiceName)
+// profileName is the profile chosen for this run, if any.
+var profileName string
+
+// SetProfile chooses the profile the CLI uses for this run, such as with
+// `--profile`. If none is chosen, it uses the current profile.
+func SetProfile(name string) {
+	profileName = name
+}
+
+// MustGetProfileStore returns the store of the CLI's profiles.
+func MustGetProfileStore() profile.Store {
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("obtaining user home directory: %s", err)
+	}
+
+	return profile.NewStore(path.Join(homedir, ".guppy"))
+}
+
+// MustGetProfile returns the profile chosen with [SetProfile], or else the
+// current profile.
+func MustGetProfile() profile.Profile {
+	store := MustGetProfileStore()
+
+	name := profileName
+	if name == "" {
+		var err error
+		name, err = store.Current()
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	serviceURL, err := url.Parse(serviceURLStr)
+	p, err := store.Get(name)
 	if err != nil {
-		log.Fatal(err)
+		if errors.Is(err, profile.ErrNotFound) {
+			log.Fatalf("%s\nCreate it with `guppy profile create %s`, or list profiles with `guppy profile ls`.", err, name)
+		}
+		log.Fatalf("loading profile: %s", err)
+	}
+	return p
+}
+
+// MustGetDataPath returns the path of the file the CLI stores agent data in,
+// which belongs to the profile in use.
+func MustGetDataPath() string {
+	return MustGetProfile().DataPath()
+}
+
+// setting returns the value of the environment variable `env` if it's set, or
+// else the configured value if there is one, or else `def`.
+func setting(env string, configured string, def string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if configured != "" {
+		return configured
 	}
+	return def
+}
 
-	serviceDIDStr := os.Getenv("STORACHA_SERVICE_DID")
-	if serviceDIDStr == "" {
-		serviceDIDStr = fmt.Sprintf("did:web:%s", defaultServiceName)
+func mustGetServiceURL() *url.URL {
+	serviceURL, err := url.Parse(setting("STORACHA_SERVICE_URL", MustGetProfile().Config.ServiceURL, client.DefaultServiceURL))
+	if err != nil {
+		log.Fatal(err)
 	}
+	return serviceURL
+}
+
+// MustGetConnection returns a connection to the service, as given by the
+// environment, the config, or else the default.
+func MustGetConnection() uclient.Connection {
+	// service URL & DID
+	serviceURL := mustGetServiceURL()
 
-	servicePrincipal, err := did.Parse(serviceDIDStr)
+	servicePrincipal, err := did.Parse(setting("STORACHA_SERVICE_DID", MustGetProfile().Config.ServiceDID, client.DefaultServiceDID))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -138,10 +220,12 @@ func MustGetConnection() uclient.Connection {
 	return conn
 }
 
+// MustGetReceiptsURL returns the URL to fetch receipts from, as given by the
+// environment or the config, or else the service's receipt endpoint.
 func MustGetReceiptsURL() *url.URL {
-	receiptsURLStr := os.Getenv("STORACHA_RECEIPTS_URL")
+	receiptsURLStr := setting("STORACHA_RECEIPTS_URL", MustGetProfile().Config.ReceiptsURL, "")
 	if receiptsURLStr == "" {
-		receiptsURLStr = fmt.Sprintf("https://%s/receipt", defaultServiceName)
+		return mustGetServiceURL().JoinPath("receipt")
 	}
 
 	receiptsURL, err := url.Parse(receiptsURLStr)
@@ -152,6 +236,38 @@ func MustGetReceiptsURL() *url.URL {
 	return receiptsURL
 }
 
+// IntSetting returns the value of the command's int flag `name` if it's given
+// on the command line or in its environment variable, or else the configured
+// value of the setting of the same name if there is one, or else the flag's
+// default.
+func IntSetting(cCtx *cli.Context, name string) int {
+	if cCtx.IsSet(name) {
+		return cCtx.Int(name)
+	}
+	v, err := MustGetProfile().Config.Get(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if v == "" {
+		return cCtx.Int(name)
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("config setting %s: %s", name, err)
+	}
+	return i
+}
+
+// JSONOutput reports whether the command should output JSON, as given by its
+// `--json` flag, or else the environment variable GUPPY_OUTPUT, or else the
+// configured output format.
+func JSONOutput(cCtx *cli.Context) bool {
+	if cCtx.IsSet("json") {
+		return cCtx.Bool("json")
+	}
+	return setting("GUPPY_OUTPUT", MustGetProfile().Config.Output, config.OutputText) == config.OutputJSON
+}
+
 func MustParseDID(str string) did.DID {
 	did, err := did.Parse(str)
 	if err != nil {
@@ -160,6 +276,61 @@ func MustParseDID(str string) did.DID {
 	return did
 }
 
+// MustGetSpace resolves the space given by name or DID, as for a `--space`
+// flag, falling back as [GetSpace] does, with the profile's configured space.
+func MustGetSpace(c *client.Client, nameOrDID string) did.DID {
+	space, err := GetSpace(c, nameOrDID, MustGetProfile().Config.Space)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return space
+}
+
+// GetSpace resolves the space given by name or DID. If none is given, it falls
+// back to the environment variable GUPPY_SPACE, then the `configured` space,
+// then the client's current space, as chosen with `guppy space use`.
+func GetSpace(c *client.Client, nameOrDID string, configured string) (did.DID, error) {
+	if nameOrDID == "" {
+		nameOrDID = os.Getenv("GUPPY_SPACE")
+	}
+	if nameOrDID == "" {
+		nameOrDID = configured
+	}
+	if nameOrDID == "" {
+		if space, ok := c.CurrentSpace(); ok {
+			return space, nil
+		}
+		return did.Undef, errors.New("no space given: pass --space, or choose a current space with `guppy space use`")
+	}
+
+	space, err := c.ResolveSpace(nameOrDID)
+	if err != nil {
+		return did.Undef, fmt.Errorf("resolving space: %w", err)
+	}
+	return space, nil
+}
+
+func MustParseCID(str string) ipld.Link {
+	c, err := cid.Parse(str)
+	if err != nil {
+		log.Fatalf("parsing CID: %s", err)
+	}
+	return cidlink.Link{Cid: c}
+}
+
+// MustParseDigest parses a blob digest, given either as a base58btc encoded
+// multihash or as a CID, such as the CID of a shard.
+func MustParseDigest(str string) multihash.Multihash {
+	if c, err := cid.Decode(str); err == nil {
+		return c.Hash()
+	}
+	digest, err := multihash.FromB58String(str)
+	if err != nil {
+		log.Fatalf("parsing digest: %s", err)
+	}
+	return digest
+}
+
 func MustGetProof(path string) delegation.Delegation {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,83 @@
+package cmdutil_test
+
+import (
+	"testing"
+
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/storacha/guppy/pkg/agentdata"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSpace(t *testing.T) {
+	newClient := func(t *testing.T) (*client.Client, did.DID, did.DID) {
+		c, err := client.NewClient()
+		require.NoError(t, err)
+		current, _, err := c.CreateSpace("current")
+		require.NoError(t, err)
+		configured, _, err := c.CreateSpace("configured")
+		require.NoError(t, err)
+		return c, current.DID(), configured.DID()
+	}
+
+	t.Run("prefers the given space", func(t *testing.T) {
+		c, _, configured := newClient(t)
+		t.Setenv("GUPPY_SPACE", "current")
+
+		space, err := cmdutil.GetSpace(c, "configured", "current")
+		require.NoError(t, err)
+		require.Equal(t, configured, space)
+	})
+
+	t.Run("then the environment", func(t *testing.T) {
+		c, _, configured := newClient(t)
+		t.Setenv("GUPPY_SPACE", configured.String())
+
+		space, err := cmdutil.GetSpace(c, "", "current")
+		require.NoError(t, err)
+		require.Equal(t, configured, space)
+	})
+
+	t.Run("then the configured space over the current one", func(t *testing.T) {
+		c, _, configured := newClient(t)
+		t.Setenv("GUPPY_SPACE", "")
+
+		space, err := cmdutil.GetSpace(c, "", "configured")
+		require.NoError(t, err)
+		require.Equal(t, configured, space)
+	})
+
+	t.Run("then the current space", func(t *testing.T) {
+		c, current, _ := newClient(t)
+		t.Setenv("GUPPY_SPACE", "")
+
+		space, err := cmdutil.GetSpace(c, "", "")
+		require.NoError(t, err)
+		require.Equal(t, current, space)
+	})
+
+	t.Run("then the configured space without a current one", func(t *testing.T) {
+		configured, err := signer.Generate()
+		require.NoError(t, err)
+		c, err := client.NewClient(client.WithData(agentdata.AgentData{
+			Spaces: map[string]did.DID{"configured": configured.DID()},
+		}))
+		require.NoError(t, err)
+		t.Setenv("GUPPY_SPACE", "")
+
+		space, err := cmdutil.GetSpace(c, "", "configured")
+		require.NoError(t, err)
+		require.Equal(t, configured.DID(), space)
+	})
+
+	t.Run("fails without any space", func(t *testing.T) {
+		c, err := client.NewClient()
+		require.NoError(t, err)
+		t.Setenv("GUPPY_SPACE", "")
+
+		_, err = cmdutil.GetSpace(c, "", "")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,61 @@
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// GetPassphrase returns the passphrase for encrypted agent data from the
+// environment variable GUPPY_PASSPHRASE, if set, or else prompts for it on the
+// terminal.
+func GetPassphrase() ([]byte, error) {
+	if p := os.Getenv("GUPPY_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	return promptPassphrase("Passphrase: ")
+}
+
+// GetNewPassphrase returns a new passphrase to encrypt agent data with from
+// the environment variable GUPPY_PASSPHRASE, if set, or else prompts for it on
+// the terminal twice, to confirm it.
+func GetNewPassphrase() ([]byte, error) {
+	if p := os.Getenv("GUPPY_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+
+	passphrase, err := promptPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	confirmation, err := promptPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if string(confirmation) != string(passphrase) {
+		return nil, errors.New("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+func promptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no terminal to prompt for a passphrase on, set GUPPY_PASSPHRASE instead")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,232 @@
+// Package config reads and writes the CLI's configuration file, which holds
+// settings used when they're given neither as flags nor in the environment.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/storacha/go-ucanto/did"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownKey is returned when getting or setting a key which isn't a
+// setting.
+var ErrUnknownKey = errors.New("unknown config key")
+
+// Output formats.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Config holds the CLI's settings. Empty settings are unset, and fall back to
+// the CLI's defaults.
+type Config struct {
+	// ServiceURL is the URL of the service to invoke capabilities on.
+	ServiceURL string `yaml:"service-url,omitempty"`
+	// ServiceDID is the DID of the service.
+	ServiceDID string `yaml:"service-did,omitempty"`
+	// ReceiptsURL is the URL to fetch receipts from.
+	ReceiptsURL string `yaml:"receipts-url,omitempty"`
+	// Space is the name or DID of the space commands act on when none is
+	// given, in place of the agent's current space.
+	Space string `yaml:"space,omitempty"`
+	// ShardSize is the approximate size in bytes of the CAR files uploads are
+	// sharded into.
+	ShardSize int `yaml:"shard-size,omitempty"`
+	// Parallel is the number of shards to upload at once.
+	Parallel int `yaml:"parallel,omitempty"`
+	// Output is the format of commands' output, [OutputText] or [OutputJSON].
+	Output string `yaml:"output,omitempty"`
+}
+
+// Setting describes a key of the config.
+type Setting struct {
+	Key   string
+	Usage string
+
+	get func(*Config) string
+	set func(*Config, string) error
+}
+
+// Settings are the keys of the config, in the order they're listed in.
+var Settings = []Setting{
+	{
+		Key:   "service-url",
+		Usage: "URL of the service to use.",
+		get:   func(c *Config) string { return c.ServiceURL },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := url.ParseRequestURI(v); err != nil {
+					return fmt.Errorf("parsing URL: %w", err)
+				}
+			}
+			c.ServiceURL = v
+			return nil
+		},
+	},
+	{
+		Key:   "service-did",
+		Usage: "DID of the service to use.",
+		get:   func(c *Config) string { return c.ServiceDID },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := did.Parse(v); err != nil {
+					return fmt.Errorf("parsing DID: %w", err)
+				}
+			}
+			c.ServiceDID = v
+			return nil
+		},
+	},
+	{
+		Key:   "receipts-url",
+		Usage: "URL to fetch receipts from.",
+		get:   func(c *Config) string { return c.ReceiptsURL },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := url.ParseRequestURI(v); err != nil {
+					return fmt.Errorf("parsing URL: %w", err)
+				}
+			}
+			c.ReceiptsURL = v
+			return nil
+		},
+	},
+	{
+		Key:   "space",
+		Usage: "Name or DID of the space to act on when no --space is given. Overrides the space chosen with `space use`.",
+		get:   func(c *Config) string { return c.Space },
+		set: func(c *Config, v string) error {
+			c.Space = v
+			return nil
+		},
+	},
+	{
+		Key:   "shard-size",
+		Usage: "Shard uploads into CAR files of approximately this size in bytes.",
+		get:   func(c *Config) string { return formatInt(c.ShardSize) },
+		set: func(c *Config, v string) (err error) {
+			c.ShardSize, err = parseInt(v, 0)
+			return err
+		},
+	},
+	{
+		Key:   "parallel",
+		Usage: "Number of shards to upload at once.",
+		get:   func(c *Config) string { return formatInt(c.Parallel) },
+		set: func(c *Config, v string) (err error) {
+			c.Parallel, err = parseInt(v, 1)
+			return err
+		},
+	},
+	{
+		Key:   "output",
+		Usage: "Format of commands' output: text or json.",
+		get:   func(c *Config) string { return c.Output },
+		set: func(c *Config, v string) error {
+			if v != "" && v != OutputText && v != OutputJSON {
+				return fmt.Errorf("invalid output format %q, expected %q or %q", v, OutputText, OutputJSON)
+			}
+			c.Output = v
+			return nil
+		},
+	},
+}
+
+func setting(key string) (Setting, error) {
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+	return Setting{}, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+}
+
+// Get returns the value of the setting `key`, or "" if it's unset.
+func (c Config) Get(key string) (string, error) {
+	s, err := setting(key)
+	if err != nil {
+		return "", err
+	}
+	return s.get(&c), nil
+}
+
+// Set sets the setting `key` to `value`, after checking it's valid. Setting it
+// to "" unsets it.
+func (c *Config) Set(key string, value string) error {
+	s, err := setting(key)
+	if err != nil {
+		return err
+	}
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Load reads the config in the file at `path`. If the file doesn't exist, the
+// config is empty.
+func Load(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return c, fmt.Errorf("reading config: %w", err)
+	}
+
+	// Reject unknown keys, so that typos don't go unnoticed.
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return c, fmt.Errorf("decoding config from %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the config to the file at `path`, creating its directory if
+// needed.
+func (c Config) Save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+func formatInt(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+func parseInt(v string, least int) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parsing integer: %w", err)
+	}
+	if i < least {
+		return 0, fmt.Errorf("must be at least %d, got %d", least, i)
+	}
+	return i, nil
+}
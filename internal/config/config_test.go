@@ -0,0 +1,70 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/storacha/guppy/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("round-trips settings through the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "guppy", "config.yaml")
+
+		var cfg config.Config
+		require.NoError(t, cfg.Set("service-url", "https://staging.up.storacha.network"))
+		require.NoError(t, cfg.Set("service-did", "did:web:staging.up.storacha.network"))
+		require.NoError(t, cfg.Set("shard-size", "104857600"))
+		require.NoError(t, cfg.Set("parallel", "4"))
+		require.NoError(t, cfg.Set("output", "json"))
+		require.NoError(t, cfg.Save(path))
+
+		loaded, err := config.Load(path)
+		require.NoError(t, err)
+		require.Equal(t, cfg, loaded)
+
+		v, err := loaded.Get("shard-size")
+		require.NoError(t, err)
+		require.Equal(t, "104857600", v)
+
+		v, err = loaded.Get("space")
+		require.NoError(t, err)
+		require.Empty(t, v, "expected an unset setting to be empty")
+	})
+
+	t.Run("loads a missing file as empty", func(t *testing.T) {
+		cfg, err := config.Load(filepath.Join(t.TempDir(), "config.yaml"))
+		require.NoError(t, err)
+		require.Equal(t, config.Config{}, cfg)
+	})
+
+	t.Run("unsets settings set to empty", func(t *testing.T) {
+		cfg := config.Config{Parallel: 4, Space: "photos"}
+		require.NoError(t, cfg.Set("parallel", ""))
+		require.NoError(t, cfg.Set("space", ""))
+		require.Equal(t, config.Config{}, cfg)
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		var cfg config.Config
+		require.ErrorIs(t, cfg.Set("colour", "blue"), config.ErrUnknownKey)
+		require.ErrorContains(t, cfg.Set("parallel", "0"), "at least 1")
+		require.ErrorContains(t, cfg.Set("shard-size", "big"), "parsing integer")
+		require.ErrorContains(t, cfg.Set("output", "xml"), "invalid output format")
+		require.ErrorContains(t, cfg.Set("service-did", "web:example"), "parsing DID")
+		require.Equal(t, config.Config{}, cfg)
+
+		_, err := cfg.Get("colour")
+		require.ErrorIs(t, err, config.ErrUnknownKey)
+	})
+
+	t.Run("rejects unknown keys in the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("paralel: 4\n"), 0600))
+
+		_, err := config.Load(path)
+		require.ErrorContains(t, err, "paralel")
+	})
+}
@@ -0,0 +1,220 @@
+// Package profile manages the CLI's named profiles, each of which has its own
+// agent data and config.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/storacha/guppy/internal/config"
+)
+
+// Default is the name of the profile used when none is chosen. Its data lives
+// at the root of the store, where it lived before there were profiles.
+const Default = "default"
+
+// ErrNotFound is returned when a profile doesn't exist.
+var ErrNotFound = errors.New("profile not found")
+
+// ErrExists is returned when creating a profile which already exists.
+var ErrExists = errors.New("profile already exists")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Profile is a named set of agent data and configuration.
+type Profile struct {
+	Name string
+	// Config holds the profile's settings.
+	Config config.Config
+
+	dir string
+}
+
+// DataPath returns the path of the file the profile's agent data is stored in.
+func (p Profile) DataPath() string {
+	return filepath.Join(p.dir, "config.json")
+}
+
+// ConfigPath returns the path of the file the profile's config is stored in.
+func (p Profile) ConfigPath() string {
+	return filepath.Join(p.dir, "config.yaml")
+}
+
+// Store holds profiles in a directory, such as `~/.guppy`.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store of profiles in `dir`.
+func NewStore(dir string) Store {
+	return Store{dir: dir}
+}
+
+func (s Store) profileDir(name string) string {
+	if name == Default {
+		return s.dir
+	}
+	return filepath.Join(s.dir, "profiles", name)
+}
+
+func (s Store) currentPath() string {
+	return filepath.Join(s.dir, "current-profile")
+}
+
+// Get returns the named profile. The default profile always exists.
+func (s Store) Get(name string) (Profile, error) {
+	if err := checkName(name); err != nil {
+		return Profile{}, err
+	}
+
+	p := Profile{Name: name, dir: s.profileDir(name)}
+	if name != Default {
+		if _, err := os.Stat(p.dir); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return Profile{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+			}
+			return Profile{}, fmt.Errorf("reading profile %s: %w", name, err)
+		}
+	}
+
+	var err error
+	p.Config, err = config.Load(p.ConfigPath())
+	if err != nil {
+		return Profile{}, fmt.Errorf("loading profile %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// List returns all profiles, the default profile first and the rest sorted by
+// name.
+func (s Store) List() ([]Profile, error) {
+	def, err := s.Get(Default)
+	if err != nil {
+		return nil, err
+	}
+	profiles := []Profile{def}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "profiles"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading profiles: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || checkName(entry.Name()) != nil {
+			continue
+		}
+		p, err := s.Get(entry.Name())
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	slices.SortStableFunc(profiles[1:], func(a, b Profile) int { return strings.Compare(a.Name, b.Name) })
+
+	return profiles, nil
+}
+
+// Create creates a profile with the given name and config. It fails with
+// [ErrExists] if the profile already exists, as the default profile always
+// does.
+func (s Store) Create(p Profile) (Profile, error) {
+	if err := checkName(p.Name); err != nil {
+		return Profile{}, err
+	}
+	p.dir = s.profileDir(p.Name)
+
+	if _, err := s.Get(p.Name); err == nil {
+		return Profile{}, fmt.Errorf("%w: %s", ErrExists, p.Name)
+	} else if !errors.Is(err, ErrNotFound) {
+		return Profile{}, err
+	}
+
+	if err := os.MkdirAll(p.dir, 0700); err != nil {
+		return Profile{}, fmt.Errorf("creating profile directory: %w", err)
+	}
+	if err := p.Config.Save(p.ConfigPath()); err != nil {
+		return Profile{}, fmt.Errorf("saving profile %s: %w", p.Name, err)
+	}
+
+	return p, nil
+}
+
+// Remove removes the named profile, including its agent data. The default
+// profile can't be removed. If the profile is the current one, the default
+// profile becomes current.
+func (s Store) Remove(name string) error {
+	if name == Default {
+		return errors.New("the default profile can't be removed")
+	}
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(s.profileDir(name)); err != nil {
+		return fmt.Errorf("removing profile %s: %w", name, err)
+	}
+
+	current, err := s.Current()
+	if err != nil {
+		return err
+	}
+	if current == name {
+		return s.Use(Default)
+	}
+	return nil
+}
+
+// Current returns the name of the current profile, which is used when none is
+// chosen explicitly.
+func (s Store) Current() (string, error) {
+	b, err := os.ReadFile(s.currentPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Default, nil
+		}
+		return "", fmt.Errorf("reading current profile: %w", err)
+	}
+
+	name := strings.TrimSpace(string(b))
+	if name == "" {
+		return Default, nil
+	}
+	return name, nil
+}
+
+// Use makes the named profile the current one.
+func (s Store) Use(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+
+	if name == Default {
+		if err := os.Remove(s.currentPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("resetting current profile: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	if err := os.WriteFile(s.currentPath(), []byte(name+"\n"), 0600); err != nil {
+		return fmt.Errorf("writing current profile: %w", err)
+	}
+	return nil
+}
+
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: must start with a letter or digit, and contain only letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package profile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/storacha/guppy/internal/config"
+	"github.com/storacha/guppy/internal/profile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store := profile.NewStore(dir)
+
+	t.Run("has a default profile at the root", func(t *testing.T) {
+		def, err := store.Get(profile.Default)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "config.json"), def.DataPath())
+
+		current, err := store.Current()
+		require.NoError(t, err)
+		require.Equal(t, profile.Default, current)
+	})
+
+	t.Run("creates, uses and removes profiles", func(t *testing.T) {
+		prod, err := store.Create(profile.Profile{
+			Name: "production",
+			Config: config.Config{
+				ServiceURL: "https://up.storacha.network",
+				ServiceDID: "did:web:up.storacha.network",
+			},
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, filepath.Join(dir, "config.json"), prod.DataPath())
+
+		_, err = store.Create(profile.Profile{Name: "production"})
+		require.ErrorIs(t, err, profile.ErrExists)
+		_, err = store.Create(profile.Profile{Name: profile.Default})
+		require.ErrorIs(t, err, profile.ErrExists)
+
+		got, err := store.Get("production")
+		require.NoError(t, err)
+		require.Equal(t, prod, got)
+
+		_, err = store.Create(profile.Profile{Name: "alice"})
+		require.NoError(t, err)
+
+		profiles, err := store.List()
+		require.NoError(t, err)
+		var names []string
+		for _, p := range profiles {
+			names = append(names, p.Name)
+		}
+		require.Equal(t, []string{profile.Default, "alice", "production"}, names)
+
+		require.NoError(t, store.Use("production"))
+		current, err := store.Current()
+		require.NoError(t, err)
+		require.Equal(t, "production", current)
+
+		require.NoError(t, os.WriteFile(prod.DataPath(), []byte("{}"), 0600))
+		require.NoError(t, store.Remove("production"))
+		_, err = store.Get("production")
+		require.ErrorIs(t, err, profile.ErrNotFound)
+		_, err = os.Stat(prod.DataPath())
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		current, err = store.Current()
+		require.NoError(t, err)
+		require.Equal(t, profile.Default, current, "expected removing the current profile to make the default current")
+	})
+
+	t.Run("rejects invalid names", func(t *testing.T) {
+		_, err := store.Create(profile.Profile{Name: "../escape"})
+		require.ErrorContains(t, err, "invalid profile name")
+
+		require.Error(t, store.Use("missing"))
+		require.Error(t, store.Remove(profile.Default))
+	})
+}
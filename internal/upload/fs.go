@@ -0,0 +1,176 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// localFS is an [fs.FS] over the local paths given to `guppy up`. It is what
+// the preparation pipeline scans and reads from.
+//
+// If `entries` is nil, the root of the FS is the single path `root`, which may
+// be a file or a directory. Otherwise, the root is a virtual directory whose
+// children are the given paths, keyed by their base names. This is how
+// multiple paths, or a single wrapped file, end up under a single upload root.
+type localFS struct {
+	root    string
+	entries map[string]string
+	hidden  bool
+	// modTime is reported as the modification time of the virtual root.
+	modTime time.Time
+}
+
+var _ fs.StatFS = localFS{}
+var _ fs.ReadDirFS = localFS{}
+
+// newLocalFS returns a [localFS] rooted at a single file or directory.
+func newLocalFS(path string, hidden bool) localFS {
+	return localFS{root: path, hidden: hidden}
+}
+
+// newWrappingFS returns a [localFS] whose root is a virtual directory
+// containing each of the given paths.
+func newWrappingFS(paths []string, hidden bool) (localFS, error) {
+	entries := make(map[string]string, len(paths))
+	for _, p := range paths {
+		name := filepath.Base(filepath.Clean(p))
+		if _, ok := entries[name]; ok {
+			return localFS{}, fmt.Errorf("multiple paths named %q", name)
+		}
+		entries[name] = p
+	}
+	return localFS{
+		entries: entries,
+		hidden:  hidden,
+		modTime: time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// resolve returns the OS path for the given FS path. It returns false if the
+// name refers to the virtual root directory.
+func (l localFS) resolve(op, name string) (string, bool, error) {
+	if !fs.ValidPath(name) {
+		return "", false, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if !l.hidden && name != "." {
+		for _, elem := range strings.Split(name, "/") {
+			if isHidden(elem) {
+				return "", false, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+		}
+	}
+
+	if l.entries == nil {
+		return filepath.Join(l.root, filepath.FromSlash(name)), true, nil
+	}
+
+	if name == "." {
+		return "", false, nil
+	}
+
+	first, rest, _ := strings.Cut(name, "/")
+	base, ok := l.entries[first]
+	if !ok {
+		return "", false, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return filepath.Join(base, filepath.FromSlash(rest)), true, nil
+}
+
+func (l localFS) Open(name string) (fs.File, error) {
+	osPath, ok, err := l.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return virtualDir{virtualDirInfo{l.modTime}}, nil
+	}
+	return os.Open(osPath)
+}
+
+func (l localFS) Stat(name string) (fs.FileInfo, error) {
+	osPath, ok, err := l.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return virtualDirInfo{l.modTime}, nil
+	}
+	return os.Stat(osPath)
+}
+
+func (l localFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	osPath, ok, err := l.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		entries := make([]fs.DirEntry, 0, len(l.entries))
+		for name, p := range l.entries {
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, renamedDirEntry{fs.FileInfoToDirEntry(info), name})
+		}
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+		return entries, nil
+	}
+
+	entries, err := os.ReadDir(osPath)
+	if err != nil {
+		return nil, err
+	}
+	if l.hidden {
+		return entries, nil
+	}
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		return isHidden(e.Name())
+	}), nil
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// renamedDirEntry is a [fs.DirEntry] presented under a different name.
+type renamedDirEntry struct {
+	fs.DirEntry
+	name string
+}
+
+func (r renamedDirEntry) Name() string {
+	return r.name
+}
+
+// virtualDir is the [fs.File] for the virtual root directory of a wrapping
+// [localFS].
+type virtualDir struct {
+	info virtualDirInfo
+}
+
+func (d virtualDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (virtualDir) Read([]byte) (int, error)     { return 0, io.EOF }
+func (virtualDir) Close() error                 { return nil }
+
+// virtualDirInfo is the [fs.FileInfo] for the virtual root directory of a
+// wrapping [localFS].
+type virtualDirInfo struct {
+	modTime time.Time
+}
+
+func (virtualDirInfo) Name() string         { return "." }
+func (virtualDirInfo) Size() int64          { return 0 }
+func (virtualDirInfo) Mode() fs.FileMode    { return fs.ModeDir | 0755 }
+func (i virtualDirInfo) ModTime() time.Time { return i.modTime }
+func (virtualDirInfo) IsDir() bool          { return true }
+func (virtualDirInfo) Sys() any             { return nil }
@@ -2,15 +2,22 @@ package upload
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 
 	"github.com/ipfs/go-cid"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multicodec"
 	"github.com/multiformats/go-multihash"
+	"github.com/storacha/go-libstoracha/blobindex"
 	"github.com/storacha/go-ucanto/core/car"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/ipld"
@@ -18,7 +25,13 @@ import (
 	"github.com/storacha/guppy/internal/cmdutil"
 	"github.com/storacha/guppy/pkg/car/sharding"
 	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/preparation"
+	configurationsmodel "github.com/storacha/guppy/pkg/preparation/configurations/model"
+	"github.com/storacha/guppy/pkg/preparation/sqlrepo"
+	preparationuploads "github.com/storacha/guppy/pkg/preparation/uploads"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+	_ "modernc.org/sqlite"
 )
 
 // Upload handles file and directory uploads to Storacha.
@@ -26,7 +39,6 @@ import (
 // pulled out of `main` without much refactoring, as uploading is still
 // evolving. It should end up implemented within `pkg` before long.
 func Upload(cCtx *cli.Context) error {
-	space := cmdutil.MustParseDID(cCtx.String("space"))
 	proofs := []delegation.Delegation{}
 	if cCtx.String("proof") != "" {
 		proof := cmdutil.MustGetProof(cCtx.String("proof"))
@@ -34,13 +46,21 @@ func Upload(cCtx *cli.Context) error {
 	}
 
 	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
 
 	// Handle options
 	isCAR := cCtx.String("car") != ""
-	isJSON := cCtx.Bool("json")
+	isJSON := cmdutil.JSONOutput(cCtx)
 	// isVerbose := cCtx.Bool("verbose")
 	isWrap := cCtx.Bool("wrap")
-	// shardSize := cCtx.Int("shard-size")
+	opts := prepareOptions{
+		hidden:    cCtx.Bool("hidden"),
+		shardSize: uint64(cmdutil.IntSetting(cCtx, "shard-size")),
+		parallel:  cmdutil.IntSetting(cCtx, "parallel"),
+	}
+	if opts.parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1, got %d", opts.parallel)
+	}
 
 	var paths []string
 	if isCAR {
@@ -53,20 +73,24 @@ func Upload(cCtx *cli.Context) error {
 	if isCAR {
 		fmt.Printf("Uploading %s...\n", paths[0])
 		var err error
-		root, err = uploadCAR(cCtx.Context, paths[0], c, space)
+		root, err = uploadCAR(cCtx.Context, paths[0], c, space, opts.parallel)
 		if err != nil {
 			return err
 		}
 	} else {
+		if len(paths) == 0 {
+			return fmt.Errorf("no paths given to upload")
+		}
+
 		if len(paths) == 1 && !isWrap {
 			var err error
-			root, err = uploadFile(cCtx.Context, paths[0], c, space)
+			root, err = uploadFile(cCtx.Context, paths[0], c, space, opts)
 			if err != nil {
 				return err
 			}
 		} else {
 			var err error
-			root, err = uploadDirectory(cCtx.Context, paths, c, space)
+			root, err = uploadDirectory(cCtx.Context, paths, c, space, opts)
 			if err != nil {
 				return err
 			}
@@ -82,7 +106,7 @@ func Upload(cCtx *cli.Context) error {
 	return nil
 }
 
-func uploadCAR(ctx context.Context, path string, c *client.Client, space did.DID) (ipld.Link, error) {
+func uploadCAR(ctx context.Context, path string, c *client.Client, space did.DID, parallel int) (ipld.Link, error) {
 	f0, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("opening file: %w", err)
@@ -109,12 +133,26 @@ func uploadCAR(ctx context.Context, path string, c *client.Client, space did.DID
 		return nil, fmt.Errorf("missing root CID")
 	}
 
+	index := blobindex.NewShardedDagIndexView(roots[0], -1)
+
 	if stat.Size() < sharding.ShardSize {
+		// Decoding read past the header; the blob is the whole file.
+		if _, err := f0.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewinding file: %w", err)
+		}
+
 		hash, err := addBlob(ctx, f0, c, space)
 		if err != nil {
 			return nil, err
 		}
 
+		if _, err := f0.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewinding file: %w", err)
+		}
+		if err := indexShard(index, hash, f0); err != nil {
+			return nil, err
+		}
+
 		link := cidlink.Link{Cid: cid.NewCidV1(uint64(multicodec.Car), hash)}
 
 		shdlnks = append(shdlnks, link)
@@ -124,23 +162,93 @@ func uploadCAR(ctx context.Context, path string, c *client.Client, space did.DID
 			return nil, fmt.Errorf("sharding CAR: %w", err)
 		}
 
+		// Each shard is written to a temporary file, digesting it on the way, so
+		// that it can be streamed to the service and read again to index it
+		// without being held in memory. Up to `parallel` shards are in flight at
+		// once.
+		shdDir, err := os.MkdirTemp("", "guppy-shards-")
+		if err != nil {
+			return nil, fmt.Errorf("creating shard directory: %w", err)
+		}
+		defer os.RemoveAll(shdDir)
+
+		eg, egCtx := errgroup.WithContext(ctx)
+		eg.SetLimit(max(parallel, 1))
+		var mu sync.Mutex
+		var links []ipld.Link
+
 		for shd, err := range shds {
 			if err != nil {
-				return nil, fmt.Errorf("ranging shards: %w", err)
+				return nil, errors.Join(fmt.Errorf("ranging shards: %w", err), eg.Wait())
+			}
+			if egCtx.Err() != nil {
+				break
 			}
 
-			hash, err := addBlob(ctx, shd, c, space)
+			shdPath, digest, size, err := writeShard(shdDir, shd)
 			if err != nil {
-				return nil, fmt.Errorf("uploading shard: %w", err)
+				return nil, errors.Join(err, eg.Wait())
 			}
 
-			link := cidlink.Link{Cid: cid.NewCidV1(uint64(multicodec.Car), hash)}
+			// Keep the shards in order, however their uploads finish. Earlier
+			// shards' uploads may be setting their links meanwhile, so growing the
+			// slice needs the lock too.
+			mu.Lock()
+			i := len(links)
+			links = append(links, nil)
+			mu.Unlock()
+
+			eg.Go(func() error {
+				defer os.Remove(shdPath)
+				f, err := os.Open(shdPath)
+				if err != nil {
+					return fmt.Errorf("opening shard: %w", err)
+				}
+				defer f.Close()
 
-			shdlnks = append(shdlnks, link)
+				// If the PUT fails, it's retried with the shard read again from the
+				// start.
+				hash, _, err := c.SpaceBlobAdd(egCtx, f, space,
+					client.WithPrecomputedDigest(digest, size),
+					client.WithReopen(func() (io.ReadCloser, error) { return os.Open(shdPath) }),
+				)
+				if err != nil {
+					return fmt.Errorf("uploading shard: %w", err)
+				}
+
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					return fmt.Errorf("rewinding shard: %w", err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err := indexShard(index, hash, f); err != nil {
+					return err
+				}
+				links[i] = cidlink.Link{Cid: cid.NewCidV1(uint64(multicodec.Car), hash)}
+				return nil
+			})
 		}
+
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+		shdlnks = links
+	}
+
+	archive, err := index.Archive()
+	if err != nil {
+		return nil, fmt.Errorf("archiving index: %w", err)
+	}
+
+	indexHash, err := addBlob(ctx, archive, c, space)
+	if err != nil {
+		return nil, fmt.Errorf("uploading index: %w", err)
 	}
 
-	// TODO: build, add and register index
+	err = c.SpaceIndexAdd(ctx, space, cidlink.Link{Cid: cid.NewCidV1(uint64(multicodec.Car), indexHash)})
+	if err != nil {
+		return nil, fmt.Errorf("registering index: %w", err)
+	}
 
 	addOk, err := c.UploadAdd(
 		ctx,
@@ -156,12 +264,181 @@ func uploadCAR(ctx context.Context, path string, c *client.Client, space did.DID
 	return addOk.Root, nil
 }
 
-func uploadFile(ctx context.Context, path string, c *client.Client, space did.DID) (ipld.Link, error) {
-	return nil, errors.New("not implemented")
+// writeShard writes the shard CAR read from `r` to a new file in `dir`. It
+// returns the path of the file, and the SHA2-256 multihash and size of the CAR.
+func writeShard(dir string, r io.Reader) (string, multihash.Multihash, uint64, error) {
+	f, err := os.CreateTemp(dir, "shard-*.car")
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("creating shard file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hasher), r)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("writing shard: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", nil, 0, fmt.Errorf("writing shard: %w", err)
+	}
+
+	digest, err := multihash.Encode(hasher.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("encoding shard digest: %w", err)
+	}
+	return f.Name(), digest, uint64(size), nil
 }
 
-func uploadDirectory(ctx context.Context, paths []string, c *client.Client, space did.DID) (ipld.Link, error) {
-	return nil, errors.New("not implemented")
+// indexShard adds the position of every block in the shard CAR read from `r`
+// to the index, under the shard's digest.
+func indexShard(index blobindex.ShardedDagIndexView, shardHash multihash.Multihash, r io.Reader) error {
+	_, blocks, err := car.Decode(r)
+	if err != nil {
+		return fmt.Errorf("decoding shard: %w", err)
+	}
+
+	for blk, err := range blocks {
+		if err != nil {
+			return fmt.Errorf("reading shard block: %w", err)
+		}
+
+		carBlk, ok := blk.(car.CarBlock)
+		if !ok {
+			return fmt.Errorf("block %s has no position in shard", blk.Link())
+		}
+
+		blkLink, ok := blk.Link().(cidlink.Link)
+		if !ok {
+			return fmt.Errorf("block link %s is not a CID", blk.Link())
+		}
+
+		index.SetSlice(shardHash, blkLink.Cid.Hash(), blobindex.Position{
+			Offset: carBlk.Offset(),
+			Length: carBlk.Length(),
+		})
+	}
+
+	return nil
+}
+
+// prepareOptions are the options for uploads. All but `parallel` only apply to
+// uploads which go through the preparation pipeline.
+type prepareOptions struct {
+	// hidden includes paths that start with ".".
+	hidden bool
+	// shardSize is the approximate size of each shard, or 0 for the default.
+	shardSize uint64
+	// parallel is how many shards may be uploaded at once.
+	parallel int
+}
+
+// uploadFile uploads a single file, or a single directory, with no wrapping
+// directory. The upload's root is the file or directory itself.
+func uploadFile(ctx context.Context, path string, c *client.Client, space did.DID, opts prepareOptions) (ipld.Link, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+
+	return prepareAndUpload(ctx, path, newLocalFS(path, opts.hidden), c, space, opts)
+}
+
+// uploadDirectory uploads the given paths. A single directory is uploaded as
+// the root itself, while multiple paths, or a single file, are wrapped in a
+// directory.
+func uploadDirectory(ctx context.Context, paths []string, c *client.Client, space did.DID, opts prepareOptions) (ipld.Link, error) {
+	if len(paths) == 1 {
+		stat, err := os.Stat(paths[0])
+		if err != nil {
+			return nil, fmt.Errorf("stat file: %w", err)
+		}
+		if stat.IsDir() {
+			return prepareAndUpload(ctx, paths[0], newLocalFS(paths[0], opts.hidden), c, space, opts)
+		}
+	}
+
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("stat file: %w", err)
+		}
+	}
+
+	fsys, err := newWrappingFS(paths, opts.hidden)
+	if err != nil {
+		return nil, err
+	}
+
+	return prepareAndUpload(ctx, ".", fsys, c, space, opts)
+}
+
+// prepareAndUpload runs the contents of `fsys` through the preparation
+// pipeline, which scans it, builds the UnixFS DAG, shards it, adds the shards
+// to the space, and registers the upload. It returns the upload's root.
+//
+// The preparation database only lives as long as the upload.
+func prepareAndUpload(ctx context.Context, name string, fsys fs.FS, c *client.Client, space did.DID, opts prepareOptions) (ipld.Link, error) {
+	dbDir, err := os.MkdirTemp("", "guppy-up-")
+	if err != nil {
+		return nil, fmt.Errorf("creating preparation database directory: %w", err)
+	}
+	defer os.RemoveAll(dbDir)
+
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(dbDir, "preparation.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("opening preparation database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, sqlrepo.Schema); err != nil {
+		return nil, fmt.Errorf("creating preparation database schema: %w", err)
+	}
+
+	repo := sqlrepo.New(db)
+
+	api := preparation.NewAPI(
+		repo,
+		c,
+		space,
+		preparation.WithGetLocalFSForPathFn(func(path string) (fs.FS, error) {
+			return fsys, nil
+		}),
+		preparation.WithShardParallelism(opts.parallel),
+	)
+
+	var configOpts []configurationsmodel.ConfigurationOption
+	if opts.shardSize > 0 {
+		configOpts = append(configOpts, configurationsmodel.WithShardSize(opts.shardSize))
+	}
+
+	configuration, err := api.CreateConfiguration(ctx, name, configOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating configuration: %w", err)
+	}
+
+	source, err := api.CreateSource(ctx, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("creating source: %w", err)
+	}
+
+	if err := repo.AddSourceToConfiguration(ctx, configuration.ID(), source.ID()); err != nil {
+		return nil, fmt.Errorf("adding source to configuration: %w", err)
+	}
+
+	uploads, err := api.CreateUploads(ctx, configuration.ID())
+	if err != nil {
+		return nil, fmt.Errorf("creating uploads: %w", err)
+	}
+	if len(uploads) != 1 {
+		return nil, fmt.Errorf("expected exactly one upload, got %d", len(uploads))
+	}
+
+	// Building the DAG is CPU-bound, so scan as many files at once as there are
+	// CPUs.
+	rootCID, err := api.ExecuteUpload(ctx, uploads[0], preparationuploads.WithDAGScanConcurrency(runtime.NumCPU()))
+	if err != nil {
+		return nil, fmt.Errorf("executing upload: %w", err)
+	}
+
+	return cidlink.Link{Cid: rootCID}, nil
 }
 
 func addBlob(ctx context.Context, content io.Reader, c *client.Client, space did.DID) (multihash.Multihash, error) {
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/storacha/guppy/pkg/agentdata"
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:  "key",
+		Usage: "Manage how this agent's private key is stored.",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "encrypt",
+				Usage:  "Encrypt this agent's stored data, including its private key, with a passphrase. Set GUPPY_PASSPHRASE to give the passphrase without being prompted.",
+				Action: keyEncrypt,
+			},
+			{
+				Name:   "decrypt",
+				Usage:  "Decrypt this agent's stored data, storing it unencrypted again.",
+				Action: keyDecrypt,
+			},
+		},
+	})
+}
+
+func keyEncrypt(cCtx *cli.Context) error {
+	datapath := cmdutil.MustGetDataPath()
+
+	// Check before asking for a passphrase, although it's checked again with the
+	// file locked.
+	encrypted, err := agentdata.IsEncrypted(datapath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no agent data to encrypt at %s", datapath)
+		}
+		return fmt.Errorf("reading agent data: %w", err)
+	}
+	if encrypted {
+		return fmt.Errorf("agent data at %s is already encrypted", datapath)
+	}
+
+	passphrase, err := cmdutil.GetNewPassphrase()
+	if err != nil {
+		return fmt.Errorf("getting passphrase: %w", err)
+	}
+
+	err = agentdata.EncryptFile(datapath, func() ([]byte, error) { return passphrase, nil })
+	if err != nil {
+		if errors.Is(err, agentdata.ErrAlreadyEncrypted) {
+			return fmt.Errorf("agent data at %s is already encrypted", datapath)
+		}
+		return fmt.Errorf("encrypting agent data: %w", err)
+	}
+
+	fmt.Printf("Encrypted agent data at %s\n", datapath)
+
+	return nil
+}
+
+func keyDecrypt(cCtx *cli.Context) error {
+	datapath := cmdutil.MustGetDataPath()
+
+	err := agentdata.DecryptFile(datapath, cmdutil.GetPassphrase)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return fmt.Errorf("no agent data to decrypt at %s", datapath)
+		case errors.Is(err, agentdata.ErrNotEncrypted):
+			return fmt.Errorf("agent data at %s is not encrypted", datapath)
+		}
+		return fmt.Errorf("decrypting agent data: %w", err)
+	}
+
+	fmt.Printf("Decrypted agent data at %s\n", datapath)
+
+	return nil
+}
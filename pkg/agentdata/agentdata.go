@@ -5,46 +5,112 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/multiformats/go-varint"
 	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
 	"github.com/storacha/go-ucanto/principal"
 	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
 	rsasigner "github.com/storacha/go-ucanto/principal/rsa/signer"
 )
 
+// Version is the version of the serialization [AgentData.MarshalJSON] writes.
+// Data without a version was written before versioning was introduced, and
+// holds only a principal and delegations.
+const Version = 1
+
 type AgentData struct {
 	Principal   principal.Signer
 	Delegations []delegation.Delegation
+	// Spaces maps the names given to spaces to their DIDs.
+	Spaces map[string]did.DID
+	// CurrentSpace is the space commands act on when none is given, or
+	// [did.Undef] if there isn't one.
+	CurrentSpace did.DID
+	// Issued are the delegations the agent issued to others, kept so that they
+	// can be revoked.
+	Issued []delegation.Delegation
+}
+
+// Clone returns a copy of the agent data which doesn't share its delegations,
+// issued delegations or spaces with it.
+func (ad AgentData) Clone() AgentData {
+	ad.Delegations = slices.Clone(ad.Delegations)
+	ad.Issued = slices.Clone(ad.Issued)
+	ad.Spaces = maps.Clone(ad.Spaces)
+	return ad
 }
 
 type agentDataSerialized struct {
-	Principal   []byte
-	Delegations [][]byte
+	Version      int
+	Principal    []byte
+	Delegations  [][]byte
+	Spaces       map[string]string `json:",omitempty"`
+	CurrentSpace string            `json:",omitempty"`
+	Issued       [][]byte          `json:",omitempty"`
 }
 
 func (ad AgentData) MarshalJSON() ([]byte, error) {
-	delegations := make([][]byte, 0, len(ad.Delegations))
-	for _, d := range ad.Delegations {
-		b, err := io.ReadAll(d.Archive())
+	delegations, err := archiveDelegations(ad.Delegations)
+	if err != nil {
+		return nil, err
+	}
+
+	var issued [][]byte
+	if len(ad.Issued) > 0 {
+		issued, err = archiveDelegations(ad.Issued)
 		if err != nil {
-			return nil, fmt.Errorf("reading delegation archive: %w", err)
+			return nil, err
 		}
-		delegations = append(delegations, b)
+	}
+
+	var spaces map[string]string
+	if len(ad.Spaces) > 0 {
+		spaces = make(map[string]string, len(ad.Spaces))
+		for name, space := range ad.Spaces {
+			spaces[name] = space.String()
+		}
+	}
+
+	var currentSpace string
+	if ad.CurrentSpace != did.Undef {
+		currentSpace = ad.CurrentSpace.String()
 	}
 
 	return json.Marshal(agentDataSerialized{
-		Principal:   ad.Principal.Encode(),
-		Delegations: delegations,
+		Version:      Version,
+		Principal:    ad.Principal.Encode(),
+		Delegations:  delegations,
+		Spaces:       spaces,
+		CurrentSpace: currentSpace,
+		Issued:       issued,
 	})
 }
 
+func archiveDelegations(dels []delegation.Delegation) ([][]byte, error) {
+	archives := make([][]byte, 0, len(dels))
+	for _, d := range dels {
+		b, err := io.ReadAll(d.Archive())
+		if err != nil {
+			return nil, fmt.Errorf("reading delegation archive: %w", err)
+		}
+		archives = append(archives, b)
+	}
+	return archives, nil
+}
+
 func (ad *AgentData) UnmarshalJSON(b []byte) error {
 	var s agentDataSerialized
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
+	if s.Version > Version {
+		return fmt.Errorf("unsupported agent data version %d, expected at most %d", s.Version, Version)
+	}
 
 	// Principal
 
@@ -81,25 +147,156 @@ func (ad *AgentData) UnmarshalJSON(b []byte) error {
 		ad.Delegations[i] = d
 	}
 
+	// Spaces
+
+	ad.Spaces = nil
+	if len(s.Spaces) > 0 {
+		ad.Spaces = make(map[string]did.DID, len(s.Spaces))
+		for name, str := range s.Spaces {
+			space, err := did.Parse(str)
+			if err != nil {
+				return fmt.Errorf("parsing DID of space %q: %w", name, err)
+			}
+			ad.Spaces[name] = space
+		}
+	}
+
+	ad.CurrentSpace = did.Undef
+	if s.CurrentSpace != "" {
+		ad.CurrentSpace, err = did.Parse(s.CurrentSpace)
+		if err != nil {
+			return fmt.Errorf("parsing DID of current space: %w", err)
+		}
+	}
+
+	// Issued delegations
+
+	ad.Issued = nil
+	for i, db := range s.Issued {
+		d, err := delegation.Extract(db)
+		if err != nil {
+			return fmt.Errorf("decoding issued delegation %d: %w", i, err)
+		}
+		ad.Issued = append(ad.Issued, d)
+	}
+
 	return nil
 }
 
-func (ad AgentData) WriteToFile(path string) error {
+// FileOption configures how agent data is written to and read from a file.
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	passphrase func() ([]byte, error)
+}
+
+// WithPassphrase encrypts agent data written to a file with a key derived from
+// the passphrase `passphrase` returns. When reading, `passphrase` is only
+// called if the file is encrypted.
+func WithPassphrase(passphrase func() ([]byte, error)) FileOption {
+	return func(o *fileOptions) {
+		o.passphrase = passphrase
+	}
+}
+
+func (ad AgentData) WriteToFile(path string, options ...FileOption) error {
+	var opts fileOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
 	b, err := json.Marshal(ad)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, b, 0600)
+	if opts.passphrase != nil {
+		passphrase, err := opts.passphrase()
+		if err != nil {
+			return fmt.Errorf("getting passphrase: %w", err)
+		}
+		b, err = encrypt(b, passphrase)
+		if err != nil {
+			return fmt.Errorf("encrypting agent data: %w", err)
+		}
+	}
+
+	return writeFileAtomic(path, b)
+}
+
+// writeFileAtomic writes data to the file at `path` by writing it to a
+// temporary file in the same directory and renaming that over it, so that
+// readers, and the file left by a crash, see either the old or the new data,
+// never a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("setting permissions of temporary file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
 }
 
-func ReadFromFile(path string) (AgentData, error) {
+// ReadFromFile reads agent data from a file, decrypting it if it's encrypted.
+// Reading encrypted data fails with [ErrPassphraseRequired] unless
+// [WithPassphrase] is given.
+func ReadFromFile(path string, options ...FileOption) (AgentData, error) {
+	var opts fileOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return AgentData{}, err
 	}
 
+	if isEncrypted(b) {
+		if opts.passphrase == nil {
+			return AgentData{}, fmt.Errorf("reading agent data from %s: %w", path, ErrPassphraseRequired)
+		}
+		passphrase, err := opts.passphrase()
+		if err != nil {
+			return AgentData{}, fmt.Errorf("getting passphrase: %w", err)
+		}
+		b, err = decrypt(b, passphrase)
+		if err != nil {
+			return AgentData{}, fmt.Errorf("decrypting agent data from %s: %w", path, err)
+		}
+	}
+
 	var ad AgentData
-	json.Unmarshal(b, &ad)
+	if err := json.Unmarshal(b, &ad); err != nil {
+		return AgentData{}, fmt.Errorf("decoding agent data from %s: %w", path, err)
+	}
 	return ad, nil
 }
+
+// IsEncrypted reports whether the agent data in a file is encrypted.
+func IsEncrypted(path string) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return isEncrypted(b), nil
+}
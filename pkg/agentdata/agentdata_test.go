@@ -2,10 +2,12 @@ package agentdata_test
 
 import (
 	"encoding/json"
+	"io"
 	"path"
 	"testing"
 
 	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
 	"github.com/storacha/go-ucanto/principal/ed25519/signer"
 	"github.com/storacha/guppy/pkg/agentdata"
 	"github.com/stretchr/testify/require"
@@ -57,3 +59,90 @@ func TestWriteReadAgentData(t *testing.T) {
 	require.Equal(t, agentData.Principal, agentDataReturned.Principal)
 	require.Equal(t, delegationsCids(agentData), delegationsCids(agentDataReturned))
 }
+
+func TestRoundTripAgentDataSpaces(t *testing.T) {
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	space, err := signer.Generate()
+	require.NoError(t, err)
+
+	agentData := agentdata.AgentData{
+		Principal:    agentPrincipal,
+		Spaces:       map[string]did.DID{"photos": space.DID()},
+		CurrentSpace: space.DID(),
+	}
+
+	str, err := json.Marshal(agentData)
+	require.NoError(t, err)
+
+	var agentDataReturned agentdata.AgentData
+	err = json.Unmarshal(str, &agentDataReturned)
+	require.NoError(t, err)
+
+	require.Equal(t, agentData.Spaces, agentDataReturned.Spaces)
+	require.Equal(t, agentData.CurrentSpace, agentDataReturned.CurrentSpace)
+}
+
+func TestRoundTripAgentDataIssued(t *testing.T) {
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	del, err := newDelegation()
+	require.NoError(t, err)
+
+	agentData := agentdata.AgentData{
+		Principal: agentPrincipal,
+		Issued:    []delegation.Delegation{del},
+	}
+
+	str, err := json.Marshal(agentData)
+	require.NoError(t, err)
+
+	var agentDataReturned agentdata.AgentData
+	err = json.Unmarshal(str, &agentDataReturned)
+	require.NoError(t, err)
+
+	require.Empty(t, agentDataReturned.Delegations)
+	require.Len(t, agentDataReturned.Issued, 1)
+	require.Equal(t, del.Link(), agentDataReturned.Issued[0].Link())
+}
+
+func TestReadUnversionedAgentData(t *testing.T) {
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	del, err := newDelegation()
+	require.NoError(t, err)
+	archive, err := io.ReadAll(del.Archive())
+	require.NoError(t, err)
+
+	// The format written before the data was versioned.
+	str, err := json.Marshal(map[string]any{
+		"Principal":   agentPrincipal.Encode(),
+		"Delegations": [][]byte{archive},
+	})
+	require.NoError(t, err)
+
+	var agentData agentdata.AgentData
+	err = json.Unmarshal(str, &agentData)
+	require.NoError(t, err)
+
+	require.Equal(t, agentPrincipal.DID(), agentData.Principal.DID())
+	require.Len(t, agentData.Delegations, 1)
+	require.Equal(t, del.Link().String(), agentData.Delegations[0].Link().String())
+	require.Empty(t, agentData.Spaces)
+	require.Equal(t, did.Undef, agentData.CurrentSpace)
+}
+
+func TestReadNewerAgentData(t *testing.T) {
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+
+	str, err := json.Marshal(map[string]any{
+		"Version":   agentdata.Version + 1,
+		"Principal": agentPrincipal.Encode(),
+	})
+	require.NoError(t, err)
+
+	var agentData agentdata.AgentData
+	err = json.Unmarshal(str, &agentData)
+	require.ErrorContains(t, err, "unsupported agent data version")
+}
@@ -0,0 +1,186 @@
+package agentdata
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrPassphraseRequired is returned when reading encrypted agent data without
+// a passphrase.
+var ErrPassphraseRequired = errors.New("agent data is encrypted, a passphrase is required")
+
+// ErrAlreadyEncrypted is returned when encrypting agent data which is already
+// encrypted.
+var ErrAlreadyEncrypted = errors.New("agent data is already encrypted")
+
+// ErrNotEncrypted is returned when decrypting agent data which isn't
+// encrypted.
+var ErrNotEncrypted = errors.New("agent data is not encrypted")
+
+// ErrIncorrectPassphrase is returned when encrypted agent data can't be
+// decrypted with the passphrase given, either because it's incorrect or
+// because the data has been tampered with.
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// scrypt parameters for deriving encryption keys from passphrases, as
+// recommended for interactive logins.
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptSaltLen = 16
+)
+
+// encryptedSerialized is the serialization of encrypted agent data. The
+// ciphertext is the XChaCha20-Poly1305 encryption of the agent data's JSON
+// serialization, with a key derived from the passphrase by scrypt.
+type encryptedSerialized struct {
+	Version    int
+	Encryption *encryption
+}
+
+type encryption struct {
+	KDF        string
+	N          int
+	R          int
+	P          int
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// EncryptFile encrypts the agent data in the file at `path` with a key derived
+// from the passphrase `passphrase` returns. Like [UpdateFile], it holds the
+// file's lock throughout, so that a concurrent update isn't lost.
+func EncryptFile(path string, passphrase func() ([]byte, error)) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	encrypted, err := IsEncrypted(path)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return ErrAlreadyEncrypted
+	}
+
+	data, err := ReadFromFile(path)
+	if err != nil {
+		return err
+	}
+	return data.WriteToFile(path, WithPassphrase(passphrase))
+}
+
+// DecryptFile decrypts the agent data in the file at `path` with the passphrase
+// `passphrase` returns, storing it unencrypted. Like [UpdateFile], it holds the
+// file's lock throughout, so that a concurrent update isn't lost.
+func DecryptFile(path string, passphrase func() ([]byte, error)) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	encrypted, err := IsEncrypted(path)
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return ErrNotEncrypted
+	}
+
+	data, err := ReadFromFile(path, WithPassphrase(passphrase))
+	if err != nil {
+		return err
+	}
+	return data.WriteToFile(path)
+}
+
+// isEncrypted reports whether the serialized agent data is encrypted.
+func isEncrypted(b []byte) bool {
+	var s encryptedSerialized
+	return json.Unmarshal(b, &s) == nil && s.Encryption != nil
+}
+
+func encrypt(plaintext []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return json.Marshal(encryptedSerialized{
+		Version: Version,
+		Encryption: &encryption{
+			KDF:        "scrypt",
+			N:          scryptN,
+			R:          scryptR,
+			P:          scryptP,
+			Salt:       salt,
+			Nonce:      nonce,
+			Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+		},
+	})
+}
+
+func decrypt(b []byte, passphrase []byte) ([]byte, error) {
+	var s encryptedSerialized
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if s.Version > Version {
+		return nil, fmt.Errorf("unsupported agent data version %d, expected at most %d", s.Version, Version)
+	}
+	if s.Encryption == nil {
+		return nil, ErrNotEncrypted
+	}
+	if s.Encryption.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation function %q", s.Encryption.KDF)
+	}
+	// Only the parameters encrypt writes are accepted, so that a corrupted or
+	// hostile file can't demand arbitrary memory and time to derive the key.
+	if s.Encryption.N != scryptN || s.Encryption.R != scryptR || s.Encryption.P != scryptP {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=%d, r=%d, p=%d", s.Encryption.N, s.Encryption.R, s.Encryption.P)
+	}
+
+	key, err := scrypt.Key(passphrase, s.Encryption.Salt, s.Encryption.N, s.Encryption.R, s.Encryption.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	if len(s.Encryption.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(s.Encryption.Nonce))
+	}
+
+	plaintext, err := aead.Open(nil, s.Encryption.Nonce, s.Encryption.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,130 @@
+package agentdata_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/guppy/pkg/agentdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedAgentData(t *testing.T) {
+	passphrase := func(p string) agentdata.FileOption {
+		return agentdata.WithPassphrase(func() ([]byte, error) { return []byte(p), nil })
+	}
+
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	del, err := newDelegation()
+	require.NoError(t, err)
+
+	agentData := agentdata.AgentData{
+		Principal:   agentPrincipal,
+		Delegations: []delegation.Delegation{del},
+	}
+
+	dataFilePath := path.Join(t.TempDir(), "agentdata.json")
+	require.NoError(t, agentData.WriteToFile(dataFilePath, passphrase("correct horse")))
+
+	t.Run("doesn't store the private key in the clear", func(t *testing.T) {
+		b, err := os.ReadFile(dataFilePath)
+		require.NoError(t, err)
+		require.NotContains(t, string(b), "Principal")
+
+		encrypted, err := agentdata.IsEncrypted(dataFilePath)
+		require.NoError(t, err)
+		require.True(t, encrypted)
+	})
+
+	t.Run("reads with the passphrase", func(t *testing.T) {
+		agentDataReturned, err := agentdata.ReadFromFile(dataFilePath, passphrase("correct horse"))
+		require.NoError(t, err)
+
+		require.Equal(t, agentData.Principal, agentDataReturned.Principal)
+		require.Equal(t, delegationsCids(agentData), delegationsCids(agentDataReturned))
+	})
+
+	t.Run("requires a passphrase", func(t *testing.T) {
+		_, err := agentdata.ReadFromFile(dataFilePath)
+		require.ErrorIs(t, err, agentdata.ErrPassphraseRequired)
+	})
+
+	t.Run("rejects an incorrect passphrase", func(t *testing.T) {
+		_, err := agentdata.ReadFromFile(dataFilePath, passphrase("battery staple"))
+		require.ErrorIs(t, err, agentdata.ErrIncorrectPassphrase)
+	})
+
+	t.Run("rejects scrypt parameters it didn't write", func(t *testing.T) {
+		b, err := os.ReadFile(dataFilePath)
+		require.NoError(t, err)
+		hostile := strings.Replace(string(b), `"N":32768`, `"N":1073741824`, 1)
+		require.NotEqual(t, string(b), hostile)
+		hostileFilePath := path.Join(t.TempDir(), "agentdata.json")
+		require.NoError(t, os.WriteFile(hostileFilePath, []byte(hostile), 0600))
+
+		_, err = agentdata.ReadFromFile(hostileFilePath, passphrase("correct horse"))
+		require.ErrorContains(t, err, "unsupported scrypt parameters")
+	})
+
+	t.Run("reads unencrypted data regardless of passphrase", func(t *testing.T) {
+		plainFilePath := path.Join(t.TempDir(), "agentdata.json")
+		require.NoError(t, agentData.WriteToFile(plainFilePath))
+
+		encrypted, err := agentdata.IsEncrypted(plainFilePath)
+		require.NoError(t, err)
+		require.False(t, encrypted)
+
+		agentDataReturned, err := agentdata.ReadFromFile(plainFilePath, agentdata.WithPassphrase(func() ([]byte, error) {
+			require.FailNow(t, "expected no passphrase to be asked for")
+			return nil, nil
+		}))
+		require.NoError(t, err)
+		require.Equal(t, agentData.Principal, agentDataReturned.Principal)
+	})
+}
+
+func TestEncryptFile(t *testing.T) {
+	passphrase := func(p string) func() ([]byte, error) {
+		return func() ([]byte, error) { return []byte(p), nil }
+	}
+
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	agentData := agentdata.AgentData{Principal: agentPrincipal}
+
+	dataFilePath := path.Join(t.TempDir(), "agentdata.json")
+	require.NoError(t, agentData.WriteToFile(dataFilePath))
+
+	t.Run("encrypts unencrypted data", func(t *testing.T) {
+		require.NoError(t, agentdata.EncryptFile(dataFilePath, passphrase("correct horse")))
+
+		agentDataReturned, err := agentdata.ReadFromFile(dataFilePath, agentdata.WithPassphrase(passphrase("correct horse")))
+		require.NoError(t, err)
+		require.Equal(t, agentData.Principal, agentDataReturned.Principal)
+	})
+
+	t.Run("refuses to encrypt encrypted data", func(t *testing.T) {
+		err := agentdata.EncryptFile(dataFilePath, passphrase("battery staple"))
+		require.ErrorIs(t, err, agentdata.ErrAlreadyEncrypted)
+	})
+
+	t.Run("decrypts encrypted data", func(t *testing.T) {
+		err := agentdata.DecryptFile(dataFilePath, passphrase("battery staple"))
+		require.ErrorIs(t, err, agentdata.ErrIncorrectPassphrase)
+
+		require.NoError(t, agentdata.DecryptFile(dataFilePath, passphrase("correct horse")))
+
+		encrypted, err := agentdata.IsEncrypted(dataFilePath)
+		require.NoError(t, err)
+		require.False(t, encrypted)
+	})
+
+	t.Run("refuses to decrypt unencrypted data", func(t *testing.T) {
+		err := agentdata.DecryptFile(dataFilePath, passphrase("correct horse"))
+		require.ErrorIs(t, err, agentdata.ErrNotEncrypted)
+	})
+}
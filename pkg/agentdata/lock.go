@@ -0,0 +1,29 @@
+package agentdata
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile takes an exclusive advisory lock on the file at `path`, creating it
+// if it doesn't exist, and blocking until the lock is available. The returned
+// function releases the lock.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+
+	return func() error {
+		if err := unlock(f); err != nil {
+			f.Close()
+			return fmt.Errorf("unlocking %s: %w", path, err)
+		}
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package agentdata
+
+import "os"
+
+// Platforms without file locking rely on atomic writes alone.
+
+func lock(f *os.File) error {
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
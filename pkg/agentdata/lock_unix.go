@@ -0,0 +1,16 @@
+//go:build unix
+
+package agentdata
+
+import (
+	"os"
+	"syscall"
+)
+
+func lock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
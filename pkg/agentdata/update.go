@@ -0,0 +1,94 @@
+package agentdata
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// UpdateFile reads the agent data in the file at `path`, replaces it with the
+// result of `update`, and writes it back, holding an advisory lock on
+// `path.lock` throughout so that concurrent updates, including from other
+// processes, don't overwrite each other. If the file doesn't exist yet,
+// `update` is given empty agent data. The options apply to both reading and
+// writing.
+func UpdateFile(path string, update func(AgentData) (AgentData, error), options ...FileOption) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := ReadFromFile(path, options...)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	updated, err := update(current)
+	if err != nil {
+		return fmt.Errorf("updating agent data: %w", err)
+	}
+
+	return updated.WriteToFile(path, options...)
+}
+
+// Merge applies the changes made between `base` and `ours` to `theirs`, where
+// `ours` and `theirs` were both derived from `base` independently, such as by
+// two processes which read the same file. Delegations, issued delegations and
+// named spaces added or removed in `ours` are added to or removed from
+// `theirs`, and its current space is changed if `ours` changed it. The
+// principal is taken from `ours`.
+func Merge(base, ours, theirs AgentData) AgentData {
+	merged := theirs.Clone()
+	if ours.Principal != nil {
+		merged.Principal = ours.Principal
+	}
+
+	merged.Delegations = mergeDelegations(base.Delegations, ours.Delegations, merged.Delegations)
+	merged.Issued = mergeDelegations(base.Issued, ours.Issued, merged.Issued)
+
+	for name := range base.Spaces {
+		if _, ok := ours.Spaces[name]; !ok {
+			delete(merged.Spaces, name)
+		}
+	}
+	for name, space := range ours.Spaces {
+		if prev, ok := base.Spaces[name]; ok && prev == space {
+			continue
+		}
+		if merged.Spaces == nil {
+			merged.Spaces = make(map[string]did.DID)
+		}
+		merged.Spaces[name] = space
+	}
+	if len(merged.Spaces) == 0 {
+		merged.Spaces = nil
+	}
+
+	if ours.CurrentSpace != base.CurrentSpace {
+		merged.CurrentSpace = ours.CurrentSpace
+	}
+
+	return merged
+}
+
+// mergeDelegations applies the delegations added and removed between `base`
+// and `ours` to `theirs`, which it may modify.
+func mergeDelegations(base, ours, theirs []delegation.Delegation) []delegation.Delegation {
+	contains := func(dels []delegation.Delegation, del delegation.Delegation) bool {
+		return slices.ContainsFunc(dels, func(d delegation.Delegation) bool { return d.Link().String() == del.Link().String() })
+	}
+	merged := slices.DeleteFunc(theirs, func(del delegation.Delegation) bool {
+		return contains(base, del) && !contains(ours, del)
+	})
+	for _, del := range ours {
+		if !contains(base, del) && !contains(merged, del) {
+			merged = append(merged, del)
+		}
+	}
+	return merged
+}
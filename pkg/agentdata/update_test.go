@@ -0,0 +1,119 @@
+package agentdata_test
+
+import (
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/guppy/pkg/agentdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateFileConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	dataFilePath := path.Join(dir, "agentdata.json")
+
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	require.NoError(t, agentdata.AgentData{Principal: agentPrincipal}.WriteToFile(dataFilePath))
+
+	const writers = 20
+	dels := make([]delegation.Delegation, writers)
+	for i := range dels {
+		dels[i], err = newDelegation()
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for _, del := range dels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- agentdata.UpdateFile(dataFilePath, func(ad agentdata.AgentData) (agentdata.AgentData, error) {
+				ad.Delegations = append(ad.Delegations, del)
+				return ad, nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	agentData, err := agentdata.ReadFromFile(dataFilePath)
+	require.NoError(t, err)
+	require.Equal(t, agentPrincipal, agentData.Principal)
+	require.ElementsMatch(t, delegationsCids(agentdata.AgentData{Delegations: dels}), delegationsCids(agentData), "expected no writer's delegation to be lost")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.ElementsMatch(t, []string{"agentdata.json", "agentdata.json.lock"}, names, "expected no temporary files to be left behind")
+}
+
+func TestMerge(t *testing.T) {
+	agentPrincipal, err := signer.Generate()
+	require.NoError(t, err)
+	spaceA, err := signer.Generate()
+	require.NoError(t, err)
+	spaceB, err := signer.Generate()
+	require.NoError(t, err)
+
+	kept, err := newDelegation()
+	require.NoError(t, err)
+	removed, err := newDelegation()
+	require.NoError(t, err)
+	addedByUs, err := newDelegation()
+	require.NoError(t, err)
+	addedByThem, err := newDelegation()
+	require.NoError(t, err)
+
+	issuedByUs, err := newDelegation()
+	require.NoError(t, err)
+	issuedByThem, err := newDelegation()
+	require.NoError(t, err)
+
+	base := agentdata.AgentData{
+		Principal:   agentPrincipal,
+		Delegations: []delegation.Delegation{kept, removed},
+		Spaces:      map[string]did.DID{"a": spaceA.DID()},
+	}
+	ours := agentdata.AgentData{
+		Principal:    agentPrincipal,
+		Delegations:  []delegation.Delegation{kept, addedByUs},
+		Spaces:       map[string]did.DID{"a": spaceA.DID()},
+		CurrentSpace: spaceA.DID(),
+		Issued:       []delegation.Delegation{issuedByUs},
+	}
+	theirs := agentdata.AgentData{
+		Principal:   agentPrincipal,
+		Delegations: []delegation.Delegation{kept, removed, addedByThem},
+		Spaces:      map[string]did.DID{"a": spaceA.DID(), "b": spaceB.DID()},
+		Issued:      []delegation.Delegation{issuedByThem},
+	}
+
+	merged := agentdata.Merge(base, ours, theirs)
+
+	require.Equal(t, agentPrincipal, merged.Principal)
+	require.ElementsMatch(t,
+		delegationsCids(agentdata.AgentData{Delegations: []delegation.Delegation{kept, addedByThem, addedByUs}}),
+		delegationsCids(merged),
+	)
+	require.Equal(t, map[string]did.DID{"a": spaceA.DID(), "b": spaceB.DID()}, merged.Spaces)
+	require.Equal(t, spaceA.DID(), merged.CurrentSpace)
+	require.ElementsMatch(t,
+		delegationsCids(agentdata.AgentData{Delegations: []delegation.Delegation{issuedByThem, issuedByUs}}),
+		delegationsCids(agentdata.AgentData{Delegations: merged.Issued}),
+	)
+
+	require.Len(t, theirs.Delegations, 3, "expected theirs not to be modified")
+}
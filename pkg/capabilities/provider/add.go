@@ -0,0 +1,79 @@
+// Package provider defines the `provider/*` capabilities, which aren't yet
+// defined in go-libstoracha.
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/storacha/go-libstoracha/capabilities/types"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+const AddAbility = "provider/add"
+
+// AddCaveats represents the caveats required to perform a provider/add
+// invocation.
+type AddCaveats struct {
+	// Provider is the DID of the storage provider, typically the service.
+	Provider string
+	// Consumer is the DID of the space to provision.
+	Consumer string
+}
+
+func (ac AddCaveats) ToIPLD() (datamodel.Node, error) {
+	return ipld.WrapWithRecovery(&ac, AddCaveatsType(), types.Converters...)
+}
+
+var AddCaveatsReader = schema.Struct[AddCaveats](AddCaveatsType(), nil, types.Converters...)
+
+// AddOk represents the successful response for a provider/add invocation.
+type AddOk struct {
+}
+
+func (ao AddOk) ToIPLD() (datamodel.Node, error) {
+	return ipld.WrapWithRecovery(&ao, AddOkType(), types.Converters...)
+}
+
+var AddOkReader = schema.Struct[AddOk](AddOkType(), nil, types.Converters...)
+
+// Add can be invoked by an account to provision a space with a storage
+// provider, so that storage used in the space is billed to the account.
+var Add = validator.NewCapability(
+	AddAbility,
+	schema.DIDString(schema.WithMethod("mailto")),
+	AddCaveatsReader,
+	func(claimed, delegated ucan.Capability[AddCaveats]) failure.Failure {
+		if claimed.With() != delegated.With() {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"resource '%s' doesn't match delegated '%s'",
+				claimed.With(), delegated.With(),
+			))
+		}
+
+		if !strings.HasPrefix(claimed.Nb().Consumer, "did:key:") {
+			return schema.NewSchemaError(fmt.Sprintf("expected consumer did:key but got %s", claimed.Nb().Consumer))
+		}
+
+		if delegated.Nb().Provider != "" && claimed.Nb().Provider != delegated.Nb().Provider {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"claimed provider '%s' doesn't match delegated '%s'",
+				claimed.Nb().Provider, delegated.Nb().Provider,
+			))
+		}
+
+		if delegated.Nb().Consumer != "" && claimed.Nb().Consumer != delegated.Nb().Consumer {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"claimed consumer '%s' doesn't match delegated '%s'",
+				claimed.Nb().Consumer, delegated.Nb().Consumer,
+			))
+		}
+
+		return nil
+	},
+)
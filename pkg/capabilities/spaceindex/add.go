@@ -0,0 +1,41 @@
+// Package spaceindex defines the `space/index/add` capability with a caveats
+// reader which can decode it. The definition in go-libstoracha reads caveats
+// with a schema inferred from the Go type, which doesn't match the encoded
+// caveats, so neither the service side of tests nor local validation can use
+// it.
+package spaceindex
+
+import (
+	"fmt"
+
+	indexcap "github.com/storacha/go-libstoracha/capabilities/space/index"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// Add can be invoked to register an index with the service, so that the
+// content it describes can be found through the network's indexers.
+var Add = validator.NewCapability(
+	indexcap.AddAbility,
+	schema.DIDString(),
+	indexcap.AddCaveatsReader,
+	func(claimed, delegated ucan.Capability[indexcap.AddCaveats]) failure.Failure {
+		if claimed.With() != delegated.With() {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"resource '%s' doesn't match delegated '%s'",
+				claimed.With(), delegated.With(),
+			))
+		}
+
+		if delegated.Nb().Index != nil && claimed.Nb().Index.String() != delegated.Nb().Index.String() {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"index '%s' doesn't match delegated '%s'",
+				claimed.Nb().Index, delegated.Nb().Index,
+			))
+		}
+
+		return nil
+	},
+)
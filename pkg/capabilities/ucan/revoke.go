@@ -0,0 +1,72 @@
+// Package ucan defines the `ucan/revoke` capability, which isn't yet defined
+// in go-libstoracha.
+package ucan
+
+import (
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/storacha/go-libstoracha/capabilities/types"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+const RevokeAbility = "ucan/revoke"
+
+// RevokeCaveats represents the caveats required to perform a ucan/revoke
+// invocation.
+type RevokeCaveats struct {
+	// Ucan is the CID of the delegation to revoke.
+	Ucan ipld.Link
+	// Proof links the delegations proving the authority of the invocation's
+	// resource over the revoked delegation, when that resource isn't the
+	// revoked delegation's issuer.
+	Proof []ipld.Link
+}
+
+func (rc RevokeCaveats) ToIPLD() (datamodel.Node, error) {
+	return ipld.WrapWithRecovery(&rc, RevokeCaveatsType(), types.Converters...)
+}
+
+var RevokeCaveatsReader = schema.Struct[RevokeCaveats](RevokeCaveatsType(), nil, types.Converters...)
+
+// RevokeOk represents the successful response for a ucan/revoke invocation.
+type RevokeOk struct {
+	// Time is when the revocation was recorded, in seconds since the Unix epoch.
+	Time int64
+}
+
+func (ro RevokeOk) ToIPLD() (datamodel.Node, error) {
+	return ipld.WrapWithRecovery(&ro, RevokeOkType(), types.Converters...)
+}
+
+var RevokeOkReader = schema.Struct[RevokeOk](RevokeOkType(), nil, types.Converters...)
+
+// Revoke can be invoked by the issuer of a delegation, or by a principal in
+// its proof chain, to revoke it, so that the service no longer accepts it or
+// anything derived from it.
+var Revoke = validator.NewCapability(
+	RevokeAbility,
+	schema.DIDString(),
+	RevokeCaveatsReader,
+	func(claimed, delegated ucan.Capability[RevokeCaveats]) failure.Failure {
+		if claimed.With() != delegated.With() {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"resource '%s' doesn't match delegated '%s'",
+				claimed.With(), delegated.With(),
+			))
+		}
+
+		if delegated.Nb().Ucan != nil && claimed.Nb().Ucan.String() != delegated.Nb().Ucan.String() {
+			return schema.NewSchemaError(fmt.Sprintf(
+				"claimed ucan '%s' doesn't match delegated '%s'",
+				claimed.Nb().Ucan, delegated.Nb().Ucan,
+			))
+		}
+
+		return nil
+	},
+)
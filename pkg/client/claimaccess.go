@@ -33,7 +33,7 @@ func (c *Client) ClaimAccess(ctx context.Context) ([]udelegation.Delegation, err
 
 	claimOk, failErr := result.Unwrap(res)
 	if failErr != nil {
-		return nil, fmt.Errorf("`access/claim` failed: %w", failErr)
+		return nil, newFailureError(access.Claim.Can(), failErr)
 	}
 
 	dels := make([]udelegation.Delegation, 0, len(claimOk.Delegations.Values))
@@ -113,8 +113,11 @@ func TestClaimAccess(t *testing.T) {
 		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
 		claimedDels, err := c.ClaimAccess(testContext(t))
 
-		require.ErrorContains(t, err, "`access/claim` failed with unexpected error:")
-		require.ErrorContains(t, err, "HandlerNotFoundError")
+		require.ErrorContains(t, err, "`access/claim` failed: HandlerNotFoundError")
+		var failureErr *client.FailureError
+		require.ErrorAs(t, err, &failureErr)
+		require.Equal(t, access.Claim.Can(), failureErr.Capability)
+		require.Equal(t, "HandlerNotFoundError", failureErr.Name)
 		require.Len(t, claimedDels, 0)
 	})
 }
@@ -2,8 +2,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"time"
 
 	"github.com/ipld/go-ipld-prime/schema"
 	captypes "github.com/storacha/go-libstoracha/capabilities/types"
@@ -24,14 +27,17 @@ import (
 	"github.com/storacha/guppy/pkg/agentdata"
 	"github.com/storacha/guppy/pkg/client/nodevalue"
 	receiptclient "github.com/storacha/guppy/pkg/receipt"
+	"github.com/storacha/guppy/pkg/retry"
 )
 
 type Client struct {
-	connection     uclient.Connection
-	receiptsURL    *url.URL
-	receiptsClient *receiptclient.Client
-	data           agentdata.AgentData
-	saveFn         func(agentdata.AgentData) error
+	connection      uclient.Connection
+	receiptsURL     *url.URL
+	receiptsClient  *receiptclient.Client
+	data            agentdata.AgentData
+	saveFn          func(agentdata.AgentData) error
+	retryPolicy     retry.Policy
+	localValidation bool
 }
 
 // NewClient creates a new client.
@@ -39,6 +45,7 @@ func NewClient(options ...Option) (*Client, error) {
 	c := Client{
 		connection:     DefaultConnection,
 		receiptsClient: DefaultReceiptsClient,
+		retryPolicy:    retry.DefaultPolicy,
 	}
 
 	for _, opt := range options {
@@ -86,12 +93,55 @@ func (c *Client) Proofs() []delegation.Delegation {
 	return c.data.Delegations
 }
 
+// Issued returns the delegations the client's agent issued with
+// [Client.Delegate] which haven't been revoked.
+func (c *Client) Issued() []delegation.Delegation {
+	return c.data.Issued
+}
+
 // AddProofs adds the given delegations to the client's data and saves it.
+// Delegations the client already holds, by CID, aren't added again.
 func (c *Client) AddProofs(delegations ...delegation.Delegation) error {
-	c.data.Delegations = append(c.data.Delegations, delegations...)
+	for _, del := range delegations {
+		if !slices.ContainsFunc(c.data.Delegations, sameDelegation(del)) {
+			c.data.Delegations = append(c.data.Delegations, del)
+		}
+	}
 	return c.save()
 }
 
+// PruneProofs removes the delegations which have expired from the client's
+// data, along with any duplicates of the same delegation, and saves it. It
+// returns the delegations it removed.
+func (c *Client) PruneProofs() ([]delegation.Delegation, error) {
+	now := time.Now()
+	var kept, pruned []delegation.Delegation
+	for _, del := range c.data.Delegations {
+		if expired(del, now) || slices.ContainsFunc(kept, sameDelegation(del)) {
+			pruned = append(pruned, del)
+			continue
+		}
+		kept = append(kept, del)
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+
+	c.data.Delegations = kept
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// sameDelegation returns a function reporting whether a delegation has the
+// same CID as `del`.
+func sameDelegation(del delegation.Delegation) func(delegation.Delegation) bool {
+	return func(d delegation.Delegation) bool {
+		return d.Link().String() == del.Link().String()
+	}
+}
+
 func (c *Client) save() error {
 	if c.saveFn == nil {
 		return nil
@@ -104,9 +154,15 @@ func (c *Client) save() error {
 	return nil
 }
 
+// Reset removes all delegations from the client's data and saves it. The
+// client keeps its principal, its space names, its current space and the
+// delegations it issued, which it can still revoke.
 func (c *Client) Reset() error {
 	c.data = agentdata.AgentData{
-		Principal: c.Issuer(),
+		Principal:    c.Issuer(),
+		Spaces:       c.data.Spaces,
+		CurrentSpace: c.data.CurrentSpace,
+		Issued:       c.data.Issued,
 	}
 	return c.save()
 }
@@ -120,8 +176,33 @@ func invokeAndExecute[Caveats, Out any](
 	successType schema.Type,
 	options ...delegation.Option,
 ) (result.Result[Out, failure.IPLDBuilderFailure], fx.Effects, error) {
-	pfs := make([]delegation.Proof, 0, len(c.Proofs()))
-	for _, del := range c.Proofs() {
+	return invokeAndExecuteWithProofs[Caveats, Out](ctx, c, capParser, with, caveats, successType, nil, options...)
+}
+
+// invokeAndExecuteWithProofs is like [invokeAndExecute], but also attaches
+// `proofs` to the invocation, along with the proofs selected from those the
+// client holds. It only fails with [ErrNoProof] if neither supplies a proof.
+func invokeAndExecuteWithProofs[Caveats, Out any](
+	ctx context.Context,
+	c *Client,
+	capParser validator.CapabilityParser[Caveats],
+	with ucan.Resource,
+	caveats Caveats,
+	successType schema.Type,
+	proofs []delegation.Delegation,
+	options ...delegation.Option,
+) (result.Result[Out, failure.IPLDBuilderFailure], fx.Effects, error) {
+	selected, err := c.selectProofs(capParser.Can(), with, time.Now())
+	if err != nil && !(errors.Is(err, ErrNoProof) && len(proofs) > 0) {
+		return nil, nil, err
+	}
+	for _, del := range proofs {
+		if !slices.ContainsFunc(selected, func(d delegation.Delegation) bool { return d.Link().String() == del.Link().String() }) {
+			selected = append(selected, del)
+		}
+	}
+	pfs := make([]delegation.Proof, 0, len(selected))
+	for _, del := range selected {
 		pfs = append(pfs, delegation.FromDelegation(del))
 	}
 
@@ -130,7 +211,19 @@ func invokeAndExecute[Caveats, Out any](
 		return nil, nil, fmt.Errorf("generating invocation: %w", err)
 	}
 
-	resp, err := uclient.Execute(ctx, []invocation.Invocation{inv}, c.Connection())
+	if c.localValidation {
+		if err := validateLocally(ctx, c, capParser, inv); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Only sending the invocation is retried. A receipt reporting a failure,
+	// such as an authorization failure, is a response like any other.
+	var resp uclient.ExecutionResponse
+	err = retry.Do(ctx, c.retryPolicy, func(ctx context.Context) error {
+		resp, err = uclient.Execute(ctx, []invocation.Invocation{inv}, c.Connection())
+		return err
+	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("sending invocation: %w", err)
 	}
@@ -143,7 +236,8 @@ func invokeAndExecute[Caveats, Out any](
 	// Note that this currently only treats handler execution errors nicely
 	// (errors returned from the invocation handler itself). Other standard errors
 	// (like authorization errors) go through the fallback error reporting below
-	// along with anything we can't forsee.
+	// along with anything we can't forsee, which returns them as a
+	// [FailureError] read from the generic error value.
 	reader, err := receipt.NewReceiptReaderFromTypes[Out, serverdatamodel.HandlerExecutionErrorModel](successType, serverdatamodel.HandlerExecutionErrorType(), captypes.Converters...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("generating receipt reader: %w", err)
@@ -169,7 +263,7 @@ func invokeAndExecute[Caveats, Out any](
 		if err != nil {
 			return nil, nil, fmt.Errorf("reading `%s` error output: %w", capParser.Can(), err)
 		}
-		return nil, nil, fmt.Errorf("`%s` failed with unexpected error: %#v", capParser.Can(), errorValue)
+		return nil, nil, failureErrorFromValue(capParser.Can(), errorValue)
 	}
 
 	return result.MapError(
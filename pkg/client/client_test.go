@@ -2,10 +2,13 @@ package client_test
 
 import (
 	"testing"
+	"time"
 
 	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
 	"github.com/storacha/go-ucanto/core/delegation"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
 	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
 	"github.com/storacha/guppy/pkg/agentdata"
 	"github.com/storacha/guppy/pkg/client"
 	"github.com/stretchr/testify/require"
@@ -44,3 +47,73 @@ func TestReset(t *testing.T) {
 	require.Equal(t, savedData.Principal, issuer, "expected saved principal to be the issuer")
 	require.Empty(t, savedData.Delegations, "expected saved proofs to be empty")
 }
+
+func TestAddProofsDedupes(t *testing.T) {
+	c := uhelpers.Must(client.NewClient())
+
+	del := uhelpers.Must(uploadcap.Get.Delegate(
+		c.Issuer(),
+		c.Issuer(),
+		c.Issuer().DID().String(),
+		uploadcap.GetCaveats{Root: uhelpers.RandomCID()},
+	))
+
+	require.NoError(t, c.AddProofs(del, del))
+	require.NoError(t, c.AddProofs(del))
+	require.Equal(t, []delegation.Delegation{del}, c.Proofs(), "expected the delegation to be added once")
+}
+
+func TestPruneProofs(t *testing.T) {
+	space := uhelpers.Must(ed25519signer.Generate())
+	delegate := func(c *client.Client, options ...delegation.Option) delegation.Delegation {
+		return uhelpers.Must(delegation.Delegate(
+			space,
+			c.Issuer(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/*", space.DID().String(), ucan.NoCaveats{})},
+			options...,
+		))
+	}
+
+	var saved agentdata.AgentData
+	c := uhelpers.Must(client.NewClient(client.WithSaveFn(func(data agentdata.AgentData) error {
+		saved = data
+		return nil
+	})))
+
+	expired := delegate(c, delegation.WithExpiration(int(time.Now().Add(-time.Minute).Unix())))
+	current := delegate(c, delegation.WithExpiration(int(time.Now().Add(time.Hour).Unix())))
+	forever := delegate(c, delegation.WithNoExpiration())
+	require.NoError(t, c.AddProofs(expired, current, forever))
+
+	pruned, err := c.PruneProofs()
+	require.NoError(t, err)
+	require.Equal(t, []delegation.Delegation{expired}, pruned)
+	require.Equal(t, []delegation.Delegation{current, forever}, c.Proofs())
+	require.Equal(t, []delegation.Delegation{current, forever}, saved.Delegations)
+
+	pruned, err = c.PruneProofs()
+	require.NoError(t, err)
+	require.Empty(t, pruned)
+}
+
+func TestPruneProofsRemovesStoredDuplicates(t *testing.T) {
+	c := uhelpers.Must(client.NewClient())
+	del := uhelpers.Must(uploadcap.Get.Delegate(
+		c.Issuer(),
+		c.Issuer(),
+		c.Issuer().DID().String(),
+		uploadcap.GetCaveats{Root: uhelpers.RandomCID()},
+		delegation.WithNoExpiration(),
+	))
+
+	// Data saved before delegations were deduplicated may hold duplicates.
+	c = uhelpers.Must(client.NewClient(client.WithData(agentdata.AgentData{
+		Principal:   c.Issuer(),
+		Delegations: []delegation.Delegation{del, del},
+	})))
+
+	pruned, err := c.PruneProofs()
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	require.Equal(t, []delegation.Delegation{del}, c.Proofs())
+}
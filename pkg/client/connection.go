@@ -11,17 +11,24 @@ import (
 	"github.com/storacha/guppy/pkg/receipt"
 )
 
+// The service the client connects to by default.
+const (
+	DefaultServiceURL  = "https://up.storacha.network"
+	DefaultServiceDID  = "did:web:up.storacha.network"
+	DefaultReceiptsURL = DefaultServiceURL + "/receipt"
+)
+
 var DefaultConnection uclient.Connection
 var DefaultReceiptsClient *receipt.Client
 
 func init() {
 	// service URL & DID
-	serviceURL, err := url.Parse("https://up.web3.storage")
+	serviceURL, err := url.Parse(DefaultServiceURL)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	servicePrincipal, err := did.Parse("did:web:web3.storage")
+	servicePrincipal, err := did.Parse(DefaultServiceDID)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -36,6 +43,9 @@ func init() {
 	}
 
 	DefaultConnection = conn
-	defaultReceiptsURL := serviceURL.JoinPath("receipt")
-	DefaultReceiptsClient = receipt.New(defaultReceiptsURL)
+	receiptsURL, err := url.Parse(DefaultReceiptsURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	DefaultReceiptsClient = receipt.New(receiptsURL)
 }
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// Delegate issues a delegation of the given capabilities from the client's
+// agent to the audience. The delegation is recorded in the client's data, which
+// is saved, so that it can be found by CID to revoke later with
+// [Client.Revoke].
+//
+// The delegations the client holds which grant the capabilities are included
+// as proofs, so the audience can use capabilities which were themselves
+// delegated to the agent. Capabilities on the agent's own DID need no proof.
+// If the client holds no valid delegation granting one of the capabilities,
+// Delegate fails with [ErrNoProof] rather than issue a delegation the audience
+// couldn't use.
+//
+// Options are passed on to [delegation.Delegate]: use
+// [delegation.WithExpiration] or [delegation.WithNoExpiration] to set when the
+// delegation expires, as otherwise it expires 30 seconds after it is issued,
+// and [delegation.WithNotBefore] to set when it becomes valid.
+func (c *Client) Delegate(audience did.DID, caps []ucan.Capability[ucan.NoCaveats], options ...delegation.Option) (delegation.Delegation, error) {
+	if len(caps) == 0 {
+		return nil, fmt.Errorf("no capabilities to delegate")
+	}
+
+	proofs, err := c.proofsFor(caps)
+	if err != nil {
+		return nil, err
+	}
+
+	pfs := make([]delegation.Proof, 0, len(proofs))
+	for _, proof := range proofs {
+		pfs = append(pfs, delegation.FromDelegation(proof))
+	}
+
+	del, err := delegation.Delegate(
+		c.Issuer(),
+		audience,
+		caps,
+		append(options, delegation.WithProof(pfs...))...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delegating capabilities: %w", err)
+	}
+
+	c.data.Issued = append(c.data.Issued, del)
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return del, nil
+}
+
+// proofsFor returns the delegations the client holds which authorize any of
+// the given capabilities now, failing if there is a capability none of them
+// authorizes.
+func (c *Client) proofsFor(caps []ucan.Capability[ucan.NoCaveats]) ([]delegation.Delegation, error) {
+	now := time.Now()
+	var proofs []delegation.Delegation
+	for _, cap := range caps {
+		selected, err := c.selectProofs(cap.Can(), cap.With(), now)
+		if err != nil {
+			return nil, err
+		}
+		for _, del := range selected {
+			if !slices.ContainsFunc(proofs, sameDelegation(del)) {
+				proofs = append(proofs, del)
+			}
+		}
+	}
+	return proofs, nil
+}
@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	cdg "github.com/storacha/guppy/pkg/delegation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegate(t *testing.T) {
+	audience, err := ed25519signer.Generate()
+	require.NoError(t, err)
+
+	t.Run("re-delegates a space's capabilities with the proof", func(t *testing.T) {
+		c := uhelpers.Must(client.NewClient())
+		space, spaceDel, err := c.CreateSpace("space")
+		require.NoError(t, err)
+
+		exp := int(time.Now().Add(24 * time.Hour).Unix())
+		nbf := int(time.Now().Add(time.Hour).Unix())
+		del, err := c.Delegate(
+			audience.DID(),
+			[]ucan.Capability[ucan.NoCaveats]{
+				ucan.NewCapability("upload/add", space.DID().String(), ucan.NoCaveats{}),
+				ucan.NewCapability("space/blob/add", space.DID().String(), ucan.NoCaveats{}),
+			},
+			delegation.WithExpiration(exp),
+			delegation.WithNotBefore(nbf),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, c.DID(), del.Issuer().DID())
+		require.Equal(t, audience.DID(), del.Audience().DID())
+		require.NotNil(t, del.Expiration())
+		require.Equal(t, exp, *del.Expiration())
+		require.Equal(t, nbf, del.NotBefore())
+		require.Len(t, del.Capabilities(), 2)
+		require.Len(t, del.Proofs(), 1, "expected the space delegation to be proof of both capabilities")
+		require.Equal(t, spaceDel.Link(), del.Proofs()[0])
+		require.Equal(t, []delegation.Delegation{del}, c.Issued(), "expected the delegation to be recorded as issued")
+
+		// The archive can be read back as a proof file.
+		b, err := io.ReadAll(del.Archive())
+		require.NoError(t, err)
+		extracted, err := cdg.ExtractProof(b)
+		require.NoError(t, err)
+		require.Equal(t, del.Link(), extracted.Link())
+
+		// So can the base64 form.
+		str, err := delegation.Format(del)
+		require.NoError(t, err)
+		extracted, err = cdg.ExtractProof([]byte(str + "\n"))
+		require.NoError(t, err)
+		require.Equal(t, del.Link(), extracted.Link())
+	})
+
+	t.Run("delegates capabilities on the agent itself without proof", func(t *testing.T) {
+		c := uhelpers.Must(client.NewClient())
+
+		del, err := c.Delegate(
+			audience.DID(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("*", c.DID().String(), ucan.NoCaveats{})},
+		)
+		require.NoError(t, err)
+		require.Empty(t, del.Proofs())
+	})
+
+	t.Run("fails for a capability the agent wasn't delegated", func(t *testing.T) {
+		c := uhelpers.Must(client.NewClient())
+		space, _, err := c.CreateSpace("space")
+		require.NoError(t, err)
+		other, err := ed25519signer.Generate()
+		require.NoError(t, err)
+
+		_, err = c.Delegate(
+			audience.DID(),
+			[]ucan.Capability[ucan.NoCaveats]{
+				ucan.NewCapability("upload/add", space.DID().String(), ucan.NoCaveats{}),
+				ucan.NewCapability("upload/add", other.DID().String(), ucan.NoCaveats{}),
+			},
+		)
+		require.ErrorIs(t, err, client.ErrNoProof)
+		require.ErrorContains(t, err, "`upload/add` on "+other.DID().String())
+		require.Empty(t, c.Issued())
+	})
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/result/failure"
+)
+
+var (
+	// ErrUnauthorized matches a [FailureError] reporting that the invocation
+	// wasn't authorized: its proofs were missing, expired or invalid, or it was
+	// addressed to the wrong service. Logging in again may resolve it.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrInsufficientStorage matches a [FailureError] reporting that the space
+	// doesn't have the storage left for the invocation.
+	ErrInsufficientStorage = errors.New("insufficient storage")
+	// ErrNotFound matches a [FailureError] reporting that something the
+	// invocation refers to, such as a blob or an upload, doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrNoProof is returned, without anything being sent to the service, when
+	// the client holds no valid delegation authorizing an invocation.
+	ErrNoProof = errors.New("no proof")
+)
+
+// unauthorizedNames are the names of failures which mean the invocation wasn't
+// authorized.
+var unauthorizedNames = []string{
+	"Unauthorized",
+	"InvalidAudienceError",
+	"InvalidSignature",
+	"Expired",
+	"NotValidBefore",
+	"UnavailableProof",
+	"DIDKeyResolutionError",
+}
+
+// FailureError is a failure the service reported in the receipt for an
+// invocation, as opposed to an error in sending the invocation or reading its
+// receipt. Use [errors.Is] with [ErrUnauthorized], [ErrInsufficientStorage] or
+// [ErrNotFound] to classify it, or [errors.As] to read it.
+type FailureError struct {
+	// Capability is the ability which was invoked, such as `space/blob/add`.
+	Capability string
+	// Name is the name of the failure, such as `Unauthorized`. It may be empty if
+	// the service didn't name the failure.
+	Name string
+	// Message is the failure's message.
+	Message string
+}
+
+func (e *FailureError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("`%s` failed: %s", e.Capability, e.Message)
+	}
+	return fmt.Sprintf("`%s` failed: %s: %s", e.Capability, e.Name, e.Message)
+}
+
+// Is reports whether the failure is of the kind the given sentinel error
+// stands for.
+func (e *FailureError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return slices.Contains(unauthorizedNames, e.Name)
+	case ErrInsufficientStorage:
+		return e.Name == "InsufficientStorage"
+	case ErrNotFound:
+		return strings.HasSuffix(e.Name, "NotFound")
+	default:
+		return false
+	}
+}
+
+// newFailureError returns a [FailureError] for a failure the service reported
+// for an invocation of `capability`.
+func newFailureError(capability string, f failure.Failure) *FailureError {
+	return &FailureError{
+		Capability: capability,
+		Name:       f.Name(),
+		Message:    f.Error(),
+	}
+}
+
+// failureErrorFromValue returns a [FailureError] for a failure the service
+// reported which could only be read as a generic value, such as a map with
+// `name` and `message` keys.
+func failureErrorFromValue(capability string, value any) *FailureError {
+	e := &FailureError{Capability: capability}
+	m, ok := value.(map[string]any)
+	if !ok {
+		e.Message = fmt.Sprintf("%#v", value)
+		return e
+	}
+	if name, ok := m["name"].(string); ok {
+		e.Name = name
+	}
+	if message, ok := m["message"].(string); ok {
+		e.Message = message
+	} else {
+		e.Message = fmt.Sprintf("%#v", value)
+	}
+	return e
+}
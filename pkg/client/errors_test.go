@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		sentinel error
+		matches  bool
+	}{
+		{"Unauthorized", client.ErrUnauthorized, true},
+		{"Expired", client.ErrUnauthorized, true},
+		{"InvalidAudienceError", client.ErrUnauthorized, true},
+		{"InsufficientStorage", client.ErrInsufficientStorage, true},
+		{"BlobNotFound", client.ErrNotFound, true},
+		{"UploadNotFound", client.ErrNotFound, true},
+		{"InsufficientStorage", client.ErrUnauthorized, false},
+		{"Unauthorized", client.ErrNotFound, false},
+		{"", client.ErrUnauthorized, false},
+	} {
+		t.Run(fmt.Sprintf("%s is %v: %t", tc.name, tc.sentinel, tc.matches), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &client.FailureError{Capability: "upload/add", Name: tc.name, Message: "boom"})
+			require.Equal(t, tc.matches, errors.Is(err, tc.sentinel))
+		})
+	}
+}
+
+func TestFailureErrorFromReceipt(t *testing.T) {
+	name := "InsufficientStorage"
+	connection := testutil.NewTestServerConnection(
+		server.WithServiceMethod(
+			uploadcap.Add.Can(),
+			server.Provide(
+				uploadcap.Add,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.AddCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.AddOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					return result.Error[uploadcap.AddOk](failure.FromFailureModel(fdm.FailureModel{
+						Name:    &name,
+						Message: "space has no storage left",
+					})), nil, nil
+				},
+			),
+		),
+	)
+
+	c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+	_, err := c.UploadAdd(testContext(t), c.DID(), uhelpers.RandomCID(), nil)
+
+	require.ErrorIs(t, err, client.ErrInsufficientStorage)
+	require.NotErrorIs(t, err, client.ErrUnauthorized)
+
+	var failureErr *client.FailureError
+	require.ErrorAs(t, err, &failureErr)
+	require.Equal(t, uploadcap.Add.Can(), failureErr.Capability)
+	require.Equal(t, "InsufficientStorage", failureErr.Name)
+	require.Equal(t, "space has no storage left", failureErr.Message)
+}
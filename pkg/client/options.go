@@ -5,6 +5,7 @@ import (
 	"github.com/storacha/go-ucanto/principal"
 	"github.com/storacha/guppy/pkg/agentdata"
 	"github.com/storacha/guppy/pkg/receipt"
+	"github.com/storacha/guppy/pkg/retry"
 )
 
 // Option is an option configuring a Client.
@@ -27,6 +28,29 @@ func WithReceiptsClient(receiptsClient *receipt.Client) Option {
 	}
 }
 
+// WithRetryPolicy configures how the client retries transient failures, such
+// as network errors, timeouts and 429 or 5xx responses, when sending
+// invocations, PUTting blobs and polling for receipts. If one is not provided,
+// [retry.DefaultPolicy] will be used. Use [retry.NoRetry] to disable retries.
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(c *Client) error {
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithLocalValidation configures the client to validate each invocation, with
+// the proofs attached to it, before sending it, as the service would. An
+// invocation which fails validation isn't sent, and fails with a
+// [FailureError] matching [ErrUnauthorized] which details the proof chains
+// explored and why each of them failed. See [Client.CheckAccess].
+func WithLocalValidation() Option {
+	return func(c *Client) error {
+		c.localValidation = true
+		return nil
+	}
+}
+
 // WithPrincipal configures the principal for the client to use. If one is
 // not provided, a new principal will be generated.
 func WithPrincipal(principal principal.Signer) Option {
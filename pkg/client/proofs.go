@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+)
+
+// attestAbility is the ability of the delegations a service issues to attest
+// to the validity of delegations from accounts, which can't sign them
+// themselves.
+const attestAbility = "ucan/attest"
+
+// selectProofs returns the delegations the client holds which authorize its
+// agent to invoke `ability` on `resource` at `now`, along with the
+// attestations of them. Only delegations to the agent which are valid at `now`
+// are considered. Invoking anything on the agent's own DID needs no proof.
+//
+// A delegation authorizes the invocation if it grants the ability on the
+// resource itself, or on `ucan:*` where the delegation is issued by the
+// resource or includes a chain of proofs which authorizes the invocation.
+//
+// It fails with [ErrNoProof] if no delegation authorizes the invocation.
+func (c *Client) selectProofs(ability string, resource string, now time.Time) ([]delegation.Delegation, error) {
+	if resource == c.DID().String() {
+		return nil, nil
+	}
+
+	var selected []delegation.Delegation
+	for _, del := range c.Proofs() {
+		if del.Audience().DID() != c.DID() || !validAt(del, now) {
+			continue
+		}
+		if authorizes(del, ability, resource, now) {
+			selected = append(selected, del)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: no delegation to this agent authorizes `%s` on %s", ErrNoProof, ability, resource)
+	}
+
+	for _, del := range c.Proofs() {
+		if !validAt(del, now) || slices.Contains(selected, del) {
+			continue
+		}
+		if attests(del, selected) {
+			selected = append(selected, del)
+		}
+	}
+
+	return selected, nil
+}
+
+// validAt reports whether the delegation has become valid, and not yet
+// expired, at `now`.
+func validAt(del delegation.Delegation, now time.Time) bool {
+	return !expired(del, now) && now.Unix() >= int64(del.NotBefore())
+}
+
+// expired reports whether the delegation has expired at `now`.
+func expired(del delegation.Delegation, now time.Time) bool {
+	exp := del.Expiration()
+	return exp != nil && now.Unix() >= int64(*exp)
+}
+
+// authorizes reports whether the delegation, with the chain of proofs included
+// with it, authorizes invoking `ability` on `resource` at `now`.
+func authorizes(del delegation.Delegation, ability string, resource string, now time.Time) bool {
+	for _, cap := range del.Capabilities() {
+		if !abilityCovers(cap.Can(), ability) {
+			continue
+		}
+		if cap.With() == resource {
+			return true
+		}
+		if cap.With() != "ucan:*" {
+			continue
+		}
+		if del.Issuer().DID().String() == resource {
+			return true
+		}
+
+		br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(del.Blocks()))
+		if err != nil {
+			continue
+		}
+		for _, link := range del.Proofs() {
+			proof, err := delegation.NewDelegationView(link, br)
+			if err != nil {
+				continue
+			}
+			if proof.Audience().DID() != del.Issuer().DID() || !validAt(proof, now) {
+				continue
+			}
+			if authorizes(proof, ability, resource, now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// abilityCovers reports whether the delegated ability, which may be a wildcard
+// such as `*` or `upload/*`, covers the requested ability.
+func abilityCovers(delegated string, requested string) bool {
+	if delegated == "*" || delegated == requested {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(delegated, "*")
+	return ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(requested, prefix)
+}
+
+// attests reports whether the delegation is an attestation of any of the
+// given delegations.
+func attests(del delegation.Delegation, dels []delegation.Delegation) bool {
+	for _, cap := range del.Capabilities() {
+		if cap.Can() != attestAbility {
+			continue
+		}
+		nb, ok := cap.Nb().(datamodel.Node)
+		if !ok {
+			continue
+		}
+		proofNode, err := nb.LookupByString("proof")
+		if err != nil {
+			continue
+		}
+		proof, err := proofNode.AsLink()
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(dels, func(d delegation.Delegation) bool { return d.Link().String() == proof.String() }) {
+			return true
+		}
+	}
+	return false
+}
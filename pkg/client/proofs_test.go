@@ -0,0 +1,97 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/principal"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvocationProofs(t *testing.T) {
+	var invokedProofs [][]string
+
+	connection := testutil.NewTestServerConnection(
+		server.WithServiceMethod(
+			uploadcap.Get.Can(),
+			server.Provide(
+				uploadcap.Get,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.GetCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					var links []string
+					for _, link := range inv.Proofs() {
+						links = append(links, link.String())
+					}
+					invokedProofs = append(invokedProofs, links)
+					return result.Ok[uploadcap.GetOk, failure.IPLDBuilderFailure](uploadcap.GetOk{Root: cap.Nb().Root}), nil, nil
+				},
+			),
+		),
+	)
+
+	delegateSpace := func(t *testing.T, c *client.Client, options ...delegation.Option) (principal.Signer, delegation.Delegation) {
+		space, err := ed25519signer.Generate()
+		require.NoError(t, err)
+		del, err := delegation.Delegate(
+			space,
+			c.Issuer(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/*", space.DID().String(), ucan.NoCaveats{})},
+			options...,
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.AddProofs(del))
+		return space, del
+	}
+
+	t.Run("attaches only the proofs for the invoked space", func(t *testing.T) {
+		invokedProofs = nil
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space, del := delegateSpace(t, c, delegation.WithNoExpiration())
+		delegateSpace(t, c, delegation.WithNoExpiration())
+
+		_, err := c.UploadGet(testContext(t), space.DID(), uhelpers.RandomCID())
+		require.NoError(t, err)
+
+		require.Equal(t, [][]string{{del.Link().String()}}, invokedProofs)
+	})
+
+	t.Run("attaches no proofs for the agent's own DID", func(t *testing.T) {
+		invokedProofs = nil
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		delegateSpace(t, c, delegation.WithNoExpiration())
+
+		_, err := c.UploadGet(testContext(t), c.DID(), uhelpers.RandomCID())
+		require.NoError(t, err)
+
+		require.Equal(t, [][]string{nil}, invokedProofs)
+	})
+
+	t.Run("fails locally when only an expired delegation matches", func(t *testing.T) {
+		invokedProofs = nil
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space, _ := delegateSpace(t, c, delegation.WithExpiration(int(time.Now().Add(-time.Minute).Unix())))
+
+		_, err := c.UploadGet(testContext(t), space.DID(), uhelpers.RandomCID())
+		require.ErrorIs(t, err, client.ErrNoProof)
+		require.ErrorContains(t, err, "`upload/get` on "+space.DID().String())
+
+		require.Empty(t, invokedProofs, "expected nothing to be sent to the service")
+	})
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+	providercap "github.com/storacha/guppy/pkg/capabilities/provider"
+)
+
+// ProviderAdd provisions a space with a storage provider on behalf of an
+// account, so that the space can store data, billed to the account.
+//
+// Required delegated capability proofs: `provider/add`
+//
+// The `account` is the resource the invocation applies to. It is the DID of
+// the account, such as `did:mailto:example.com:alice`, which the client has
+// logged in as.
+//
+// The `provider` is the DID of the storage provider, typically the service's
+// DID.
+//
+// The `space` is the DID of the space to provision.
+func (c *Client) ProviderAdd(ctx context.Context, account did.DID, provider did.DID, space did.DID) error {
+	res, _, err := invokeAndExecute[providercap.AddCaveats, providercap.AddOk](
+		ctx,
+		c,
+		providercap.Add,
+		account.String(),
+		providercap.AddCaveats{
+			Provider: provider.String(),
+			Consumer: space.String(),
+		},
+		providercap.AddOkType(),
+	)
+	if err != nil {
+		return fmt.Errorf("invoking and executing `provider/add`: %w", err)
+	}
+
+	_, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return newFailureError(providercap.Add.Can(), failErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/did"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	providercap "github.com/storacha/guppy/pkg/capabilities/provider"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderAdd(t *testing.T) {
+	t.Run("invokes `provider/add`", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[providercap.AddCaveats]{}
+
+		connection := testutil.NewTestServerConnection(
+			// Stand in for the account's delegation to the agent, which would come
+			// from logging in.
+			server.WithCanIssue(func(capability ucan.Capability[any], issuer did.DID) bool {
+				return true
+			}),
+			server.WithServiceMethod(
+				providercap.Add.Can(),
+				server.Provide(
+					providercap.Add,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[providercap.AddCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[providercap.AddOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[providercap.AddOk, failure.IPLDBuilderFailure](providercap.AddOk{}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		account := uhelpers.Must(did.Parse("did:mailto:example.com:alice"))
+		space := uhelpers.Must(ed25519signer.Generate()).DID()
+
+		// The client only sends invocations it holds a proof for.
+		proof := uhelpers.Must(delegation.Delegate(
+			uhelpers.Must(ed25519signer.Generate()),
+			c.Issuer(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability(providercap.Add.Can(), account.String(), ucan.NoCaveats{})},
+		))
+		require.NoError(t, c.AddProofs(proof))
+
+		err := c.ProviderAdd(testContext(t), account, connection.ID().DID(), space)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, account.String(), invokedCapabilities[0].With())
+		require.Equal(t, connection.ID().DID().String(), invokedCapabilities[0].Nb().Provider)
+		require.Equal(t, space.String(), invokedCapabilities[0].Nb().Consumer)
+	})
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// UploadRemoval describes the removal of an upload together with the storage
+// behind it.
+type UploadRemoval struct {
+	// Root is the root CID of the upload.
+	Root ipld.Link
+	// Shards are all the shards of the upload.
+	Shards []ipld.Link
+	// Orphaned are the shards of the upload which no other upload in the space
+	// references, and so are removed with it.
+	Orphaned []ipld.Link
+	// Remaining are the orphaned shards which couldn't be removed, and so are
+	// still in the space.
+	Remaining []ipld.Link
+}
+
+// PlanUploadRemoval works out what [Client.UploadRemoveWithShards] would
+// remove for the upload with the given root, without removing anything.
+//
+// Required delegated capability proofs: `upload/get`, `upload/list`
+//
+// The `space` is the resource the invocations apply to. It is typically the
+// DID of a space.
+//
+// The `root` is the root CID of the upload.
+func (c *Client) PlanUploadRemoval(ctx context.Context, space did.DID, root ipld.Link) (UploadRemoval, error) {
+	getOk, err := c.UploadGet(ctx, space, root)
+	if err != nil {
+		return UploadRemoval{}, fmt.Errorf("getting upload: %w", err)
+	}
+
+	referenced := make(map[string]struct{})
+	err = c.forEachUpload(ctx, space, func(item uploadcap.ListItem) error {
+		if item.Root.String() == root.String() {
+			return nil
+		}
+		for _, shard := range item.Shards {
+			referenced[shard.String()] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return UploadRemoval{}, fmt.Errorf("listing uploads: %w", err)
+	}
+
+	removal := UploadRemoval{
+		Root:   getOk.Root,
+		Shards: getOk.Shards,
+	}
+	for _, shard := range getOk.Shards {
+		if _, ok := referenced[shard.String()]; ok {
+			continue
+		}
+		if slices.ContainsFunc(removal.Orphaned, func(l ipld.Link) bool { return l.String() == shard.String() }) {
+			continue
+		}
+		removal.Orphaned = append(removal.Orphaned, shard)
+	}
+	return removal, nil
+}
+
+// UploadRemoveWithShards removes the upload with the given root from a space,
+// along with the blobs of its shards which no other upload in the space
+// references. Shards which other uploads share are left in place, as is the
+// upload's sharded DAG index, which the service doesn't link to the upload.
+//
+// Once the upload is removed, a failure to remove one shard doesn't stop the
+// others being removed. The returned removal then lists the shards still in the
+// space as Remaining, alongside an error naming them. They can be removed with
+// [Client.SpaceBlobRemove], as the upload no longer exists to remove them with.
+//
+// An upload registered with one of the shards after the other uploads have
+// been listed, but before the shard is removed, will lose that shard. Avoid
+// removing uploads while adding others to the same space.
+//
+// Required delegated capability proofs: `upload/get`, `upload/list`,
+// `upload/remove`, `space/blob/remove`
+//
+// The `space` is the resource the invocations apply to. It is typically the
+// DID of a space.
+//
+// The `root` is the root CID of the upload.
+func (c *Client) UploadRemoveWithShards(ctx context.Context, space did.DID, root ipld.Link) (UploadRemoval, error) {
+	removal, err := c.PlanUploadRemoval(ctx, space, root)
+	if err != nil {
+		return UploadRemoval{}, err
+	}
+
+	if _, err := c.UploadRemove(ctx, space, root); err != nil {
+		return UploadRemoval{}, fmt.Errorf("removing upload: %w", err)
+	}
+
+	var errs []error
+	for _, shard := range removal.Orphaned {
+		shardLink, ok := shard.(cidlink.Link)
+		if !ok {
+			removal.Remaining = append(removal.Remaining, shard)
+			errs = append(errs, fmt.Errorf("shard %s is not a CID link", shard))
+			continue
+		}
+		if _, err := c.SpaceBlobRemove(ctx, space, shardLink.Cid.Hash()); err != nil {
+			removal.Remaining = append(removal.Remaining, shard)
+			errs = append(errs, fmt.Errorf("removing shard %s: %w", shard, err))
+		}
+	}
+	if len(errs) > 0 {
+		return removal, fmt.Errorf("removed upload %s, but shards %v are still in the space: %w", removal.Root, removal.Remaining, errors.Join(errs...))
+	}
+
+	return removal, nil
+}
+
+// forEachUpload calls `yield` for every upload in the space, fetching each page
+// of `upload/list` in turn.
+func (c *Client) forEachUpload(ctx context.Context, space did.DID, yield func(uploadcap.ListItem) error) error {
+	var cursor *string
+	for {
+		listOk, err := c.UploadList(ctx, space, uploadcap.ListCaveats{Cursor: cursor})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range listOk.Results {
+			if err := yield(item); err != nil {
+				return err
+			}
+		}
+
+		if len(listOk.Results) == 0 || listOk.Cursor == nil || *listOk.Cursor == "" {
+			return nil
+		}
+		if cursor != nil && *cursor == *listOk.Cursor {
+			return nil
+		}
+		cursor = listOk.Cursor
+	}
+}
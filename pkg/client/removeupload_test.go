@@ -0,0 +1,202 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadsService is a test service holding the uploads of a space, which it
+// lists two to a page. It fails to remove the blobs listed in failBlobs.
+type uploadsService struct {
+	uploads        []uploadcap.ListItem
+	failBlobs      []multihash.Multihash
+	removedUploads []ipld.Link
+	removedBlobs   []multihash.Multihash
+}
+
+func (s *uploadsService) options() []server.Option {
+	return []server.Option{
+		server.WithServiceMethod(
+			uploadcap.Get.Can(),
+			server.Provide(
+				uploadcap.Get,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.GetCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					for _, u := range s.uploads {
+						if u.Root.String() == cap.Nb().Root.String() {
+							return result.Ok[uploadcap.GetOk, failure.IPLDBuilderFailure](uploadcap.GetOk{Root: u.Root, Shards: u.Shards}), nil, nil
+						}
+					}
+					return result.Error[uploadcap.GetOk](failure.FromError(errNotFound{})), nil, nil
+				},
+			),
+		),
+		server.WithServiceMethod(
+			uploadcap.List.Can(),
+			server.Provide(
+				uploadcap.List,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.ListCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.ListOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					start := 0
+					if cap.Nb().Cursor != nil {
+						start, _ = strconv.Atoi(*cap.Nb().Cursor)
+					}
+					end := min(start+2, len(s.uploads))
+					cursor := strconv.Itoa(end)
+					return result.Ok[uploadcap.ListOk, failure.IPLDBuilderFailure](uploadcap.ListOk{
+						Cursor:  &cursor,
+						Size:    uint64(end - start),
+						Results: s.uploads[start:end],
+					}), nil, nil
+				},
+			),
+		),
+		server.WithServiceMethod(
+			uploadcap.Remove.Can(),
+			server.Provide(
+				uploadcap.Remove,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.RemoveCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.RemoveOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					s.removedUploads = append(s.removedUploads, cap.Nb().Root)
+					return result.Ok[uploadcap.RemoveOk, failure.IPLDBuilderFailure](uploadcap.RemoveOk{Root: cap.Nb().Root}), nil, nil
+				},
+			),
+		),
+		server.WithServiceMethod(
+			spaceblobcap.Remove.Can(),
+			server.Provide(
+				spaceblobcap.Remove,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[spaceblobcap.RemoveCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[spaceblobcap.RemoveOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					for _, digest := range s.failBlobs {
+						if bytes.Equal(digest, cap.Nb().Digest) {
+							return result.Error[spaceblobcap.RemoveOk](failure.FromError(errors.New("blob removal failed"))), nil, nil
+						}
+					}
+					s.removedBlobs = append(s.removedBlobs, cap.Nb().Digest)
+					return result.Ok[spaceblobcap.RemoveOk, failure.IPLDBuilderFailure](spaceblobcap.RemoveOk{Size: 1}), nil, nil
+				},
+			),
+		),
+	}
+}
+
+type errNotFound struct{}
+
+func (errNotFound) Error() string { return "upload not found" }
+func (errNotFound) Name() string  { return "UploadNotFound" }
+
+func TestUploadRemoveWithShards(t *testing.T) {
+	root := uhelpers.RandomCID()
+	onlyShard := uhelpers.RandomCID()
+	sharedShard := uhelpers.RandomCID()
+	sharedOnLaterPageShard := uhelpers.RandomCID()
+
+	newService := func() *uploadsService {
+		return &uploadsService{
+			uploads: []uploadcap.ListItem{
+				{Root: root, Shards: []ipld.Link{onlyShard, sharedShard, sharedOnLaterPageShard}},
+				{Root: uhelpers.RandomCID(), Shards: []ipld.Link{sharedShard}},
+				{Root: uhelpers.RandomCID(), Shards: []ipld.Link{uhelpers.RandomCID()}},
+				{Root: uhelpers.RandomCID(), Shards: []ipld.Link{sharedOnLaterPageShard}},
+			},
+		}
+	}
+
+	t.Run("plans to remove only the shards no other upload references", func(t *testing.T) {
+		svc := newService()
+		c := uhelpers.Must(client.NewClient(client.WithConnection(testutil.NewTestServerConnection(svc.options()...))))
+
+		removal, err := c.PlanUploadRemoval(testContext(t), c.Issuer().DID(), root)
+		require.NoError(t, err)
+
+		require.Equal(t, root.String(), removal.Root.String())
+		require.Len(t, removal.Shards, 3)
+		require.Len(t, removal.Orphaned, 1)
+		require.Equal(t, onlyShard.String(), removal.Orphaned[0].String())
+
+		require.Empty(t, svc.removedUploads, "expected a plan not to remove anything")
+		require.Empty(t, svc.removedBlobs, "expected a plan not to remove anything")
+	})
+
+	t.Run("removes the upload and its orphaned shards", func(t *testing.T) {
+		svc := newService()
+		c := uhelpers.Must(client.NewClient(client.WithConnection(testutil.NewTestServerConnection(svc.options()...))))
+
+		removal, err := c.UploadRemoveWithShards(testContext(t), c.Issuer().DID(), root)
+		require.NoError(t, err)
+		require.Len(t, removal.Orphaned, 1)
+
+		require.Len(t, svc.removedUploads, 1)
+		require.Equal(t, root.String(), svc.removedUploads[0].String())
+		require.Equal(t, []multihash.Multihash{onlyShard.(cidlink.Link).Cid.Hash()}, svc.removedBlobs)
+	})
+
+	t.Run("removes the other shards when one fails, and reports the ones remaining", func(t *testing.T) {
+		failingShard := uhelpers.RandomCID()
+		otherShard := uhelpers.RandomCID()
+		svc := &uploadsService{
+			uploads: []uploadcap.ListItem{
+				{Root: root, Shards: []ipld.Link{failingShard, otherShard}},
+			},
+			failBlobs: []multihash.Multihash{failingShard.(cidlink.Link).Cid.Hash()},
+		}
+		c := uhelpers.Must(client.NewClient(client.WithConnection(testutil.NewTestServerConnection(svc.options()...))))
+
+		removal, err := c.UploadRemoveWithShards(testContext(t), c.Issuer().DID(), root)
+		require.ErrorContains(t, err, failingShard.String())
+
+		require.Equal(t, root.String(), removal.Root.String())
+		require.Len(t, removal.Orphaned, 2)
+		require.Equal(t, []ipld.Link{failingShard}, removal.Remaining)
+
+		require.Len(t, svc.removedUploads, 1)
+		require.Equal(t, []multihash.Multihash{otherShard.(cidlink.Link).Cid.Hash()}, svc.removedBlobs)
+	})
+
+	t.Run("fails without removing anything for a missing upload", func(t *testing.T) {
+		svc := newService()
+		c := uhelpers.Must(client.NewClient(client.WithConnection(testutil.NewTestServerConnection(svc.options()...))))
+
+		_, err := c.UploadRemoveWithShards(testContext(t), c.Issuer().DID(), uhelpers.RandomCID())
+		require.ErrorIs(t, err, client.ErrNotFound)
+
+		require.Empty(t, svc.removedUploads)
+		require.Empty(t, svc.removedBlobs)
+	})
+}
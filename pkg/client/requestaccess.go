@@ -47,7 +47,7 @@ func (c *Client) RequestAccess(ctx context.Context, account string) (access.Auth
 
 	authorizeOk, failErr := result.Unwrap(res)
 	if failErr != nil {
-		return access.AuthorizeOk{}, fmt.Errorf("`access/authorize` failed: %w", failErr)
+		return access.AuthorizeOk{}, newFailureError(access.Authorize.Can(), failErr)
 	}
 
 	return authorizeOk, nil
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/ucan"
+	ucancap "github.com/storacha/guppy/pkg/capabilities/ucan"
+)
+
+// Revoke revokes a delegation the client's agent issued, such as one given to
+// another agent with [Client.Delegate], so that the service no longer accepts
+// it or anything derived from it. The delegation is sent along with the
+// invocation, so the service can verify who issued it.
+//
+// Required delegated capability proofs: none, as the agent invokes
+// `ucan/revoke` on its own DID.
+//
+// Once revoked, the delegation is removed from those the client records as
+// issued, and it and any delegation the client holds which cites it as proof
+// are removed from the client's data.
+func (c *Client) Revoke(ctx context.Context, del delegation.Delegation) error {
+	if del.Issuer().DID() != c.DID() {
+		return fmt.Errorf("delegation %s was issued by %s, not this agent", del.Link(), del.Issuer().DID())
+	}
+
+	res, _, err := invokeAndExecuteWithProofs[ucancap.RevokeCaveats, ucancap.RevokeOk](
+		ctx,
+		c,
+		ucancap.Revoke,
+		c.DID().String(),
+		ucancap.RevokeCaveats{
+			Ucan: del.Link(),
+		},
+		ucancap.RevokeOkType(),
+		[]delegation.Delegation{del},
+	)
+	if err != nil {
+		return fmt.Errorf("invoking and executing `ucan/revoke`: %w", err)
+	}
+
+	if _, failErr := result.Unwrap(res); failErr != nil {
+		return newFailureError(ucancap.Revoke.Can(), failErr)
+	}
+
+	c.data.Issued = slices.DeleteFunc(c.data.Issued, sameDelegation(del))
+	c.data.Delegations = slices.DeleteFunc(c.data.Delegations, func(d delegation.Delegation) bool {
+		return d.Link().String() == del.Link().String() ||
+			slices.ContainsFunc(d.Proofs(), func(l ucan.Link) bool { return l.String() == del.Link().String() })
+	})
+	return c.save()
+}
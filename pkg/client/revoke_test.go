@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/agentdata"
+	ucancap "github.com/storacha/guppy/pkg/capabilities/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevoke(t *testing.T) {
+	var revoked []delegation.Delegation
+	connection := testutil.NewTestServerConnection(
+		server.WithServiceMethod(
+			ucancap.Revoke.Can(),
+			server.Provide(ucancap.Revoke, testutil.UCANRevokeHandler(func(del delegation.Delegation) {
+				revoked = append(revoked, del)
+			})),
+		),
+	)
+
+	ci := uhelpers.Must(ed25519signer.Generate())
+
+	t.Run("revokes a delegation the agent issued, found by CID", func(t *testing.T) {
+		revoked = nil
+
+		// Delegate and revoke with separate clients sharing saved data, as the
+		// `delegate` and `proof revoke` commands do.
+		var saved agentdata.AgentData
+		saveFn := client.WithSaveFn(func(data agentdata.AgentData) error {
+			saved = data.Clone()
+			return nil
+		})
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection), saveFn))
+		space, _, err := c.CreateSpace("space")
+		require.NoError(t, err)
+		del, err := c.Delegate(
+			ci.DID(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", space.DID().String(), ucan.NoCaveats{})},
+			delegation.WithNoExpiration(),
+		)
+		require.NoError(t, err)
+
+		c = uhelpers.Must(client.NewClient(client.WithConnection(connection), client.WithData(saved), saveFn))
+		i := slices.IndexFunc(c.Issued(), func(d delegation.Delegation) bool { return d.Link().String() == del.Link().String() })
+		require.GreaterOrEqual(t, i, 0, "expected the delegation to be found by CID among those issued")
+
+		require.NoError(t, c.Revoke(testContext(t), c.Issued()[i]))
+
+		require.Len(t, revoked, 1)
+		require.Equal(t, del.Link(), revoked[0].Link())
+		require.Empty(t, saved.Issued, "expected the revoked delegation to be forgotten")
+		require.Len(t, saved.Delegations, 1, "expected the space delegation to remain")
+	})
+
+	t.Run("refuses to revoke a delegation another principal issued", func(t *testing.T) {
+		revoked = nil
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := uhelpers.Must(ed25519signer.Generate())
+		del := uhelpers.Must(delegation.Delegate(
+			space,
+			c.Issuer(),
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/*", space.DID().String(), ucan.NoCaveats{})},
+		))
+
+		err := c.Revoke(testContext(t), del)
+		require.ErrorContains(t, err, "not this agent")
+		require.Empty(t, revoked)
+	})
+}
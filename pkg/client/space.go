@@ -0,0 +1,221 @@
+package client
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	ed25519 "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// SpaceAbilities are the abilities a new space delegates to the agent which
+// creates it.
+var SpaceAbilities = []string{
+	"space/*",
+	"blob/*",
+	"index/*",
+	"upload/*",
+	"access/*",
+	"filecoin/*",
+	"usage/*",
+}
+
+// Space is a space the client holds delegated capabilities for, or has named.
+type Space struct {
+	DID did.DID
+	// Name is the name the client has given the space, or else the name given
+	// to it when it was created, if any.
+	Name string
+}
+
+// spaceNameFact is the fact recording a space's name on the delegation which
+// creates it.
+type spaceNameFact struct {
+	name string
+}
+
+func (f spaceNameFact) ToIPLD() (map[string]datamodel.Node, error) {
+	n, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "name", qp.String(f.name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]datamodel.Node{
+		"space": n,
+	}, nil
+}
+
+// CreateSpace generates a new space, delegates [SpaceAbilities] on it to the
+// client's agent, and saves the delegation in the client's data. A named space
+// can then be referred to by its name, and if the client has no current space
+// yet, the new space becomes the current one.
+//
+// The returned signer is the space's own key. It isn't stored anywhere: it is
+// only needed to recover access to the space, or to delegate from the space
+// directly, so the caller should offer it to the user to keep somewhere safe.
+func (c *Client) CreateSpace(name string) (principal.Signer, delegation.Delegation, error) {
+	space, err := ed25519.Generate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating space key: %w", err)
+	}
+
+	caps := make([]ucan.Capability[ucan.NoCaveats], 0, len(SpaceAbilities))
+	for _, ability := range SpaceAbilities {
+		caps = append(caps, ucan.NewCapability(ability, space.DID().String(), ucan.NoCaveats{}))
+	}
+
+	del, err := delegation.Delegate(
+		space,
+		c.Issuer(),
+		caps,
+		delegation.WithNoExpiration(),
+		delegation.WithFacts([]ucan.FactBuilder{spaceNameFact{name: name}}),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("delegating space capabilities: %w", err)
+	}
+
+	c.data.Delegations = append(c.data.Delegations, del)
+	if name != "" {
+		c.setSpaceName(name, space.DID())
+	}
+	if c.data.CurrentSpace == did.Undef {
+		c.data.CurrentSpace = space.DID()
+	}
+	if err := c.save(); err != nil {
+		return nil, nil, fmt.Errorf("saving space delegation: %w", err)
+	}
+
+	return space, del, nil
+}
+
+// NameSpace gives a space a name the client can refer to it by, replacing any
+// space the name referred to before, and saves the client's data.
+func (c *Client) NameSpace(name string, space did.DID) error {
+	if name == "" {
+		return fmt.Errorf("space name must not be empty")
+	}
+	if strings.HasPrefix(name, "did:") {
+		return fmt.Errorf("space name %q must not look like a DID", name)
+	}
+	c.setSpaceName(name, space)
+	return c.save()
+}
+
+func (c *Client) setSpaceName(name string, space did.DID) {
+	if c.data.Spaces == nil {
+		c.data.Spaces = make(map[string]did.DID)
+	}
+	c.data.Spaces[name] = space
+}
+
+// UseSpace makes the given space the client's current space, and saves the
+// client's data.
+func (c *Client) UseSpace(space did.DID) error {
+	c.data.CurrentSpace = space
+	return c.save()
+}
+
+// CurrentSpace returns the client's current space, and whether it has one.
+func (c *Client) CurrentSpace() (did.DID, bool) {
+	return c.data.CurrentSpace, c.data.CurrentSpace != did.Undef
+}
+
+// ResolveSpace finds the space the client knows by the given DID or name. A
+// name the client has given with [Client.NameSpace] takes precedence over a
+// name given to a space when it was created.
+func (c *Client) ResolveSpace(nameOrDID string) (did.DID, error) {
+	if strings.HasPrefix(nameOrDID, "did:") {
+		return did.Parse(nameOrDID)
+	}
+
+	if space, ok := c.data.Spaces[nameOrDID]; ok {
+		return space, nil
+	}
+
+	var matches []did.DID
+	for _, s := range c.Spaces() {
+		if s.Name == nameOrDID {
+			matches = append(matches, s.DID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return did.Undef, fmt.Errorf("no space named %q", nameOrDID)
+	case 1:
+		return matches[0], nil
+	default:
+		return did.Undef, fmt.Errorf("more than one space is named %q, use its DID instead", nameOrDID)
+	}
+}
+
+// Spaces returns the spaces the client holds delegations for, in the order the
+// delegations were added, followed by any other spaces the client has named,
+// in order of name. A space is any `did:key:` resource of a delegated
+// capability, other than the agent itself.
+func (c *Client) Spaces() []Space {
+	var spaces []Space
+	for _, del := range c.Proofs() {
+		name := spaceName(del)
+		for _, cap := range del.Capabilities() {
+			if !strings.HasPrefix(cap.With(), "did:key:") || cap.With() == c.DID().String() {
+				continue
+			}
+			space, err := did.Parse(cap.With())
+			if err != nil {
+				continue
+			}
+
+			i := slices.IndexFunc(spaces, func(s Space) bool { return s.DID == space })
+			if i < 0 {
+				spaces = append(spaces, Space{DID: space, Name: name})
+			} else if spaces[i].Name == "" {
+				spaces[i].Name = name
+			}
+		}
+	}
+
+	names := slices.Sorted(maps.Keys(c.data.Spaces))
+	for _, name := range names {
+		space := c.data.Spaces[name]
+		i := slices.IndexFunc(spaces, func(s Space) bool { return s.DID == space })
+		if i < 0 {
+			spaces = append(spaces, Space{DID: space, Name: name})
+		} else if !slices.Contains(names, spaces[i].Name) {
+			spaces[i].Name = name
+		}
+	}
+
+	return spaces
+}
+
+// spaceName returns the name recorded on a delegation created by
+// [Client.CreateSpace], or "" if there isn't one.
+func spaceName(del delegation.Delegation) string {
+	for _, fact := range del.Facts() {
+		node, ok := fact["space"].(datamodel.Node)
+		if !ok {
+			continue
+		}
+		nameNode, err := node.LookupByString("name")
+		if err != nil {
+			continue
+		}
+		name, err := nameNode.AsString()
+		if err != nil {
+			continue
+		}
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package client_test
+
+import (
+	"testing"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/agentdata"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSpace(t *testing.T) {
+	var saved agentdata.AgentData
+	c := uhelpers.Must(client.NewClient(client.WithSaveFn(func(data agentdata.AgentData) error {
+		saved = data
+		return nil
+	})))
+
+	space, del, err := c.CreateSpace("my space")
+	require.NoError(t, err)
+
+	require.Equal(t, space.DID(), del.Issuer().DID())
+	require.Equal(t, c.DID(), del.Audience().DID())
+	require.Len(t, del.Capabilities(), len(client.SpaceAbilities))
+	for i, cap := range del.Capabilities() {
+		require.Equal(t, client.SpaceAbilities[i], cap.Can())
+		require.Equal(t, space.DID().String(), cap.With())
+	}
+
+	require.Len(t, saved.Delegations, 1, "expected the delegation to be saved")
+	require.Equal(t, del.Link(), saved.Delegations[0].Link())
+
+	require.Equal(t, map[string]did.DID{"my space": space.DID()}, saved.Spaces)
+	require.Equal(t, space.DID(), saved.CurrentSpace, "expected the first space to become current")
+
+	require.Equal(t, []client.Space{{DID: space.DID(), Name: "my space"}}, c.Spaces())
+
+	second, _, err := c.CreateSpace("second")
+	require.NoError(t, err)
+	current, ok := c.CurrentSpace()
+	require.True(t, ok)
+	require.Equal(t, space.DID(), current, "expected a later space not to replace the current one")
+
+	resolved, err := c.ResolveSpace("second")
+	require.NoError(t, err)
+	require.Equal(t, second.DID(), resolved)
+}
+
+func TestNameAndUseSpace(t *testing.T) {
+	var saved agentdata.AgentData
+	c := uhelpers.Must(client.NewClient(client.WithSaveFn(func(data agentdata.AgentData) error {
+		saved = data
+		return nil
+	})))
+
+	_, ok := c.CurrentSpace()
+	require.False(t, ok)
+
+	space, err := ed25519signer.Generate()
+	require.NoError(t, err)
+
+	require.NoError(t, c.NameSpace("photos", space.DID()))
+	require.Equal(t, map[string]did.DID{"photos": space.DID()}, saved.Spaces)
+	require.Error(t, c.NameSpace("did:key:nope", space.DID()))
+
+	resolved, err := c.ResolveSpace("photos")
+	require.NoError(t, err)
+	require.Equal(t, space.DID(), resolved)
+
+	resolved, err = c.ResolveSpace(space.DID().String())
+	require.NoError(t, err)
+	require.Equal(t, space.DID(), resolved)
+
+	_, err = c.ResolveSpace("videos")
+	require.ErrorContains(t, err, `no space named "videos"`)
+
+	require.NoError(t, c.UseSpace(space.DID()))
+	require.Equal(t, space.DID(), saved.CurrentSpace)
+	current, ok := c.CurrentSpace()
+	require.True(t, ok)
+	require.Equal(t, space.DID(), current)
+
+	require.Equal(t, []client.Space{{DID: space.DID(), Name: "photos"}}, c.Spaces())
+
+	require.NoError(t, c.Reset())
+	current, ok = c.CurrentSpace()
+	require.True(t, ok, "expected reset to keep the current space")
+	require.Equal(t, space.DID(), current)
+}
+
+func TestSpaces(t *testing.T) {
+	c := uhelpers.Must(client.NewClient())
+
+	created, _, err := c.CreateSpace("created")
+	require.NoError(t, err)
+
+	// A space delegated from elsewhere, without a name.
+	delegated, err := ed25519signer.Generate()
+	require.NoError(t, err)
+	del, err := delegation.Delegate(
+		delegated,
+		c.Issuer(),
+		[]ucan.Capability[uploadcap.GetCaveats]{
+			ucan.NewCapability(uploadcap.Get.Can(), delegated.DID().String(), uploadcap.GetCaveats{Root: uhelpers.RandomCID()}),
+		},
+	)
+	require.NoError(t, err)
+
+	// A delegation on the agent itself, which isn't a space.
+	self, err := delegation.Delegate(
+		c.Issuer(),
+		c.Issuer(),
+		[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("*", c.DID().String(), ucan.NoCaveats{})},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, c.AddProofs(del, self))
+
+	require.Equal(t, []client.Space{
+		{DID: created.DID(), Name: "created"},
+		{DID: delegated.DID()},
+	}, c.Spaces())
+}
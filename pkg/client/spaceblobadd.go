@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"iter"
@@ -30,6 +31,7 @@ import (
 	"github.com/storacha/go-ucanto/principal/ed25519/signer"
 	"github.com/storacha/go-ucanto/ucan"
 	receiptclient "github.com/storacha/guppy/pkg/receipt"
+	"github.com/storacha/guppy/pkg/retry"
 )
 
 // SpaceBlobAddOption configures options for SpaceBlobAdd.
@@ -38,6 +40,9 @@ type SpaceBlobAddOption func(*spaceBlobAddConfig)
 // spaceBlobAddConfig holds configuration for SpaceBlobAdd.
 type spaceBlobAddConfig struct {
 	putClient *http.Client
+	digest    multihash.Multihash
+	size      uint64
+	reopen    func() (io.ReadCloser, error)
 }
 
 // WithPutClient configures the HTTP client to use for uploading blobs.
@@ -47,6 +52,25 @@ func WithPutClient(client *http.Client) SpaceBlobAddOption {
 	}
 }
 
+// WithPrecomputedDigest provides the SHA2-256 multihash and size of the
+// content, computed ahead of time. The content is then only read once, as it
+// is streamed to the service, rather than being read up front to compute them.
+func WithPrecomputedDigest(digest multihash.Multihash, size uint64) SpaceBlobAddOption {
+	return func(cfg *spaceBlobAddConfig) {
+		cfg.digest = digest
+		cfg.size = size
+	}
+}
+
+// WithReopen provides a function which opens the content again from the start,
+// returning a new reader for all of it. A failed PUT of streamed content which
+// can't be rewound is then retried by streaming it again from a new reader.
+func WithReopen(reopen func() (io.ReadCloser, error)) SpaceBlobAddOption {
+	return func(cfg *spaceBlobAddConfig) {
+		cfg.reopen = reopen
+	}
+}
+
 // SpaceBlobAdd adds a blob to the service. The issuer needs proof of
 // `space/blob/add` delegated capability.
 //
@@ -55,7 +79,13 @@ func WithPutClient(client *http.Client) SpaceBlobAddOption {
 // The `space` is the resource the invocation applies to. It is typically the
 // DID of a space.
 //
-// The `content` is the blob content to be added.
+// The `content` is the blob content to be added. If the digest and size of
+// the content are given with [WithPrecomputedDigest], or if `content` is an
+// [io.Seeker], the content is streamed rather than held in memory. Otherwise,
+// it is read into memory in full. A failed PUT of the content is retried under
+// the client's retry policy only if the content can be read again, which is to
+// say if it's an [io.Seeker], it was read into memory, or it can be reopened
+// with [WithReopen].
 //
 // The `proofs` are delegation proofs to use in addition to those in the client.
 // They won't be saved in the client, only used for this invocation.
@@ -72,20 +102,19 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	}
 	putClient := cfg.putClient
 
-	contentBytes, err := io.ReadAll(content)
-	if err != nil {
-		return nil, nil, fmt.Errorf("reading content: %w", err)
-	}
-
-	contentHash, err := multihash.Sum(contentBytes, multihash.SHA2_256, -1)
-	if err != nil {
-		return nil, nil, fmt.Errorf("computing content multihash: %w", err)
+	contentHash, contentSize := cfg.digest, cfg.size
+	if contentHash == nil {
+		var err error
+		content, contentHash, contentSize, err = digestContent(content)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	caveats := spaceblobcap.AddCaveats{
 		Blob: captypes.Blob{
 			Digest: contentHash,
-			Size:   uint64(len(contentBytes)),
+			Size:   contentSize,
 		},
 	}
 
@@ -103,7 +132,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 
 	_, failErr := result.Unwrap(res)
 	if failErr != nil {
-		return nil, nil, fmt.Errorf("`space/blob/add` failed: %w", failErr)
+		return nil, nil, newFailureError(spaceblobcap.Add.Can(), failErr)
 	}
 
 	var allocateTask, putTask, acceptTask invocation.Invocation
@@ -203,7 +232,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	case allocateRcpt != nil:
 		allocateOk, err := result.Unwrap(result.MapError(allocateRcpt.Out(), failure.FromFailureModel))
 		if err != nil {
-			return nil, nil, fmt.Errorf("blob allocation failed: %w", err)
+			return nil, nil, newFailureError(blobcap.AllocateAbility, err)
 		}
 
 		address := allocateOk.Address
@@ -215,7 +244,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	case legacyAllocateRcpt != nil:
 		allocateOk, err := result.Unwrap(result.MapError(legacyAllocateRcpt.Out(), failure.FromFailureModel))
 		if err != nil {
-			return nil, nil, fmt.Errorf("blob allocation failed: %w", err)
+			return nil, nil, newFailureError(w3sblobcap.AllocateAbility, err)
 		}
 
 		address := allocateOk.Address
@@ -229,7 +258,14 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	}
 
 	if url != nil && headers != nil {
-		if err := putBlob(ctx, putClient, url, headers, contentBytes); err != nil {
+		reopen := cfg.reopen
+		if reopen == nil {
+			reopen, err = rewinder(content)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := putBlob(ctx, putClient, c.retryPolicy, url, headers, content, reopen, contentSize); err != nil {
 			return nil, nil, fmt.Errorf("putting blob: %w", err)
 		}
 	}
@@ -253,14 +289,14 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	var site ucan.Link
 	var rcptBlocks iter.Seq2[ipld.Block, error]
 	if acceptRcpt == nil && legacyAcceptRcpt == nil {
-		anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5))
+		anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5), receiptclient.WithRetryPolicy(c.retryPolicy))
 		if err != nil {
 			return nil, nil, fmt.Errorf("polling accept: %w", err)
 		}
 	} else if acceptRcpt != nil {
 		acceptOk, failErr := result.Unwrap(result.MapError(acceptRcpt.Out(), failure.FromFailureModel))
 		if failErr != nil {
-			anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5))
+			anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5), receiptclient.WithRetryPolicy(c.retryPolicy))
 			if err != nil {
 				return nil, nil, fmt.Errorf("polling accept: %w", err)
 			}
@@ -271,7 +307,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 	} else if legacyAcceptRcpt != nil {
 		acceptOk, failErr := result.Unwrap(result.MapError(legacyAcceptRcpt.Out(), failure.FromFailureModel))
 		if failErr != nil {
-			anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5))
+			anyAcceptRcpt, err = c.receiptsClient.Poll(ctx, acceptTask.Link(), receiptclient.WithRetries(5), receiptclient.WithRetryPolicy(c.retryPolicy))
 			if err != nil {
 				return nil, nil, fmt.Errorf("polling accept: %w", err)
 			}
@@ -290,7 +326,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 
 			acceptOk, err := result.Unwrap(result.MapError(acceptRcpt.Out(), failure.FromFailureModel))
 			if err != nil {
-				return nil, nil, fmt.Errorf("blob/accept failed: %w", err)
+				return nil, nil, newFailureError(blobcap.AcceptAbility, err)
 			}
 
 			site = acceptOk.Site
@@ -303,7 +339,7 @@ func (c *Client) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.
 
 			acceptOk, err := result.Unwrap(result.MapError(legacyAcceptRcpt.Out(), failure.FromFailureModel))
 			if err != nil {
-				return nil, nil, fmt.Errorf("web3.storage/blob/accept failed: %w", err)
+				return nil, nil, newFailureError(w3sblobcap.AcceptAbility, err)
 			}
 
 			site = acceptOk.Site
@@ -339,28 +375,118 @@ func getConcludeReceipt(concludeFx invocation.Invocation) (receipt.AnyReceipt, e
 	return rcpt, nil
 }
 
-func putBlob(ctx context.Context, client *http.Client, url *url.URL, headers http.Header, body []byte) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("creating upload request: %w", err)
+// digestContent computes the SHA2-256 multihash and size of the content. It
+// returns a reader for the content to be read again from the start. If the
+// content is an [io.Seeker], it's read in a streaming pass and then rewound;
+// otherwise it's read into memory.
+func digestContent(content io.Reader) (io.Reader, multihash.Multihash, uint64, error) {
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("finding content offset: %w", err)
+		}
+
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, seeker)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("reading content: %w", err)
+		}
+
+		contentHash, err := multihash.Encode(hasher.Sum(nil), multihash.SHA2_256)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("computing content multihash: %w", err)
+		}
+
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, nil, 0, fmt.Errorf("rewinding content: %w", err)
+		}
+
+		return seeker, contentHash, uint64(size), nil
 	}
 
-	for k, v := range headers {
-		req.Header.Set(k, v[0])
+	contentBytes, err := io.ReadAll(content)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("reading content: %w", err)
 	}
 
-	resp, err := client.Do(req)
+	contentHash, err := multihash.Sum(contentBytes, multihash.SHA2_256, -1)
 	if err != nil {
-		return fmt.Errorf("uploading blob: %w", err)
+		return nil, nil, 0, fmt.Errorf("computing content multihash: %w", err)
 	}
-	io.ReadAll(resp.Body)
-	resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("uploading blob: %s", resp.Status)
+	return bytes.NewReader(contentBytes), contentHash, uint64(len(contentBytes)), nil
+}
+
+// rewinder returns a function which rewinds the content to where it is now and
+// returns it, so that it can be read again, or nil if the content isn't an
+// [io.Seeker].
+func rewinder(content io.Reader) (func() (io.ReadCloser, error), error) {
+	seeker, ok := content.(io.ReadSeeker)
+	if !ok {
+		return nil, nil
 	}
 
-	return nil
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("finding start of blob: %w", err)
+	}
+
+	return func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewinding blob: %w", err)
+		}
+		// The content belongs to the caller, so it mustn't be closed.
+		return io.NopCloser(seeker), nil
+	}, nil
+}
+
+// putBlob PUTs the blob to the given URL, retrying transient failures under
+// the policy. The first attempt sends `body`, and each retry sends a new reader
+// from `reopen`. If `reopen` is nil, the body can't be sent again, so it's only
+// tried once.
+func putBlob(ctx context.Context, client *http.Client, policy retry.Policy, url *url.URL, headers http.Header, body io.Reader, reopen func() (io.ReadCloser, error), size uint64) error {
+	if reopen == nil {
+		policy = retry.NoRetry
+	}
+
+	attempt := 0
+	return retry.Do(ctx, policy, func(ctx context.Context) error {
+		attempt++
+		attemptBody := body
+		if attempt > 1 {
+			reopened, err := reopen()
+			if err != nil {
+				return retry.Permanent(fmt.Errorf("reopening blob: %w", err))
+			}
+			defer reopened.Close()
+			attemptBody = reopened
+		}
+
+		// The transport closes a body which is an [io.Closer], but the content
+		// belongs to the caller.
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), io.NopCloser(attemptBody))
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("creating upload request: %w", err))
+		}
+		req.ContentLength = int64(size)
+
+		for k, v := range headers {
+			req.Header.Set(k, v[0])
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("uploading blob: %w", err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("uploading blob: %w", retry.NewStatusError(resp))
+		}
+
+		return nil
+	})
 }
 
 func (c *Client) sendPutReceipt(ctx context.Context, putTask invocation.Invocation) error {
@@ -475,7 +601,11 @@ func (c *Client) sendPutReceipt(ctx context.Context, putTask invocation.Invocati
 		httpPutConcludeInvocation.Attach(rcptBlock)
 	}
 
-	resp, err := uclient.Execute(ctx, []invocation.Invocation{httpPutConcludeInvocation}, c.Connection())
+	var resp uclient.ExecutionResponse
+	err = retry.Do(ctx, c.retryPolicy, func(ctx context.Context) error {
+		resp, err = uclient.Execute(ctx, []invocation.Invocation{httpPutConcludeInvocation}, c.Connection())
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("executing conclude invocation: %w", err)
 	}
@@ -495,9 +625,9 @@ func (c *Client) sendPutReceipt(ctx context.Context, putTask invocation.Invocati
 		return fmt.Errorf("reading receipt: %w", err)
 	}
 
-	_, err = result.Unwrap(result.MapError(rcpt.Out(), failure.FromFailureModel))
-	if err != nil {
-		return fmt.Errorf("ucan/conclude failed: %w", err)
+	_, failErr := result.Unwrap(result.MapError(rcpt.Out(), failure.FromFailureModel))
+	if failErr != nil {
+		return newFailureError(ucancap.Conclude.Can(), failErr)
 	}
 
 	return nil
@@ -2,8 +2,11 @@ package client_test
 
 import (
 	"bytes"
+	"io"
+	"net/http"
 	"testing"
 
+	"github.com/multiformats/go-multihash"
 	"github.com/storacha/go-ucanto/core/delegation"
 	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
 	"github.com/storacha/go-ucanto/ucan"
@@ -35,3 +38,134 @@ func TestSpaceBlobAdd(t *testing.T) {
 
 	require.ElementsMatch(t, [][]byte{[]byte("test")}, testutil.ReceivedBlobs(putClient))
 }
+
+func TestSpaceBlobAddWithPrecomputedDigest(t *testing.T) {
+	space, err := ed25519signer.Generate()
+	require.NoError(t, err)
+
+	putClient := testutil.NewPutClient()
+
+	c, err := testutil.SpaceBlobAddClient()
+	require.NoError(t, err)
+
+	cap := ucan.NewCapability("*", space.DID().String(), ucan.NoCaveats{})
+	proof, err := delegation.Delegate(space, c.Issuer(), []ucan.Capability[ucan.NoCaveats]{cap}, delegation.WithNoExpiration())
+	require.NoError(t, err)
+	err = c.AddProofs(proof)
+	require.NoError(t, err)
+
+	blob := []byte("streamed test blob")
+	digest, err := multihash.Sum(blob, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+
+	// A reader which can only be read once, and isn't seekable.
+	testBlob := io.MultiReader(bytes.NewReader(blob))
+
+	returnedDigest, _, err := c.SpaceBlobAdd(
+		testContext(t),
+		testBlob,
+		space.DID(),
+		client.WithPutClient(putClient),
+		client.WithPrecomputedDigest(digest, uint64(len(blob))),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, digest, returnedDigest)
+	require.ElementsMatch(t, [][]byte{blob}, testutil.ReceivedBlobs(putClient))
+}
+
+// flakyTransport is an [http.RoundTripper] which responds to the first
+// `failures` requests with a 503, reading the request body first as a real
+// server might, and passes the rest on to `next`.
+type flakyTransport struct {
+	failures int
+	requests int
+	next     http.RoundTripper
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests++
+	if f.requests > f.failures {
+		return f.next.RoundTrip(req)
+	}
+	io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Status:     "503 Service Unavailable",
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+	}, nil
+}
+
+func TestSpaceBlobAddRetriesPut(t *testing.T) {
+	space, err := ed25519signer.Generate()
+	require.NoError(t, err)
+
+	c, err := testutil.SpaceBlobAddClient()
+	require.NoError(t, err)
+
+	cap := ucan.NewCapability("*", space.DID().String(), ucan.NoCaveats{})
+	proof, err := delegation.Delegate(space, c.Issuer(), []ucan.Capability[ucan.NoCaveats]{cap}, delegation.WithNoExpiration())
+	require.NoError(t, err)
+	err = c.AddProofs(proof)
+	require.NoError(t, err)
+
+	t.Run("retries a seekable blob", func(t *testing.T) {
+		putClient := testutil.NewPutClient()
+		flaky := &flakyTransport{failures: 1, next: putClient.Transport}
+
+		_, _, err := c.SpaceBlobAdd(testContext(t), bytes.NewReader([]byte("test")), space.DID(), client.WithPutClient(&http.Client{Transport: flaky}))
+		require.NoError(t, err)
+
+		require.Equal(t, 2, flaky.requests)
+		require.ElementsMatch(t, [][]byte{[]byte("test")}, testutil.ReceivedBlobs(putClient))
+	})
+
+	t.Run("doesn't retry a streamed blob", func(t *testing.T) {
+		putClient := testutil.NewPutClient()
+		flaky := &flakyTransport{failures: 1, next: putClient.Transport}
+
+		blob := []byte("streamed test blob")
+		digest, err := multihash.Sum(blob, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+
+		_, _, err = c.SpaceBlobAdd(
+			testContext(t),
+			io.MultiReader(bytes.NewReader(blob)),
+			space.DID(),
+			client.WithPutClient(&http.Client{Transport: flaky}),
+			client.WithPrecomputedDigest(digest, uint64(len(blob))),
+		)
+		require.ErrorContains(t, err, "503")
+
+		require.Equal(t, 1, flaky.requests)
+		require.Empty(t, testutil.ReceivedBlobs(putClient))
+	})
+
+	t.Run("retries a streamed blob which can be reopened", func(t *testing.T) {
+		putClient := testutil.NewPutClient()
+		flaky := &flakyTransport{failures: 1, next: putClient.Transport}
+
+		blob := []byte("streamed test blob")
+		digest, err := multihash.Sum(blob, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+
+		var reopens int
+		_, _, err = c.SpaceBlobAdd(
+			testContext(t),
+			io.MultiReader(bytes.NewReader(blob)),
+			space.DID(),
+			client.WithPutClient(&http.Client{Transport: flaky}),
+			client.WithPrecomputedDigest(digest, uint64(len(blob))),
+			client.WithReopen(func() (io.ReadCloser, error) {
+				reopens++
+				return io.NopCloser(io.MultiReader(bytes.NewReader(blob))), nil
+			}),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, 2, flaky.requests)
+		require.Equal(t, 1, reopens)
+		require.ElementsMatch(t, [][]byte{blob}, testutil.ReceivedBlobs(putClient))
+	})
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// SpaceBlobGet returns the details of a blob stored in a space: its size, when
+// it was added, and the invocation which added it.
+//
+// Required delegated capability proofs: `space/blob/get/0/1`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `digest` is the multihash of the blob.
+func (c *Client) SpaceBlobGet(ctx context.Context, space did.DID, digest multihash.Multihash) (spaceblobcap.GetOk, error) {
+	res, _, err := invokeAndExecute[spaceblobcap.GetCaveats, spaceblobcap.GetOk](
+		ctx,
+		c,
+		spaceblobcap.Get,
+		space.String(),
+		spaceblobcap.GetCaveats{
+			Digest: digest,
+		},
+		spaceblobcap.GetOkType(),
+	)
+	if err != nil {
+		return spaceblobcap.GetOk{}, fmt.Errorf("invoking and executing `space/blob/get/0/1`: %w", err)
+	}
+
+	getOk, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return spaceblobcap.GetOk{}, newFailureError(spaceblobcap.Get.Can(), failErr)
+	}
+
+	return getOk, nil
+}
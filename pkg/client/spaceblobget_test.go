@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	captypes "github.com/storacha/go-libstoracha/capabilities/types"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpaceBlobGet(t *testing.T) {
+	t.Run("returns the blob from `space/blob/get/0/1`'s receipt", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[spaceblobcap.GetCaveats]{}
+		cause := uhelpers.RandomCID()
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				spaceblobcap.Get.Can(),
+				server.Provide(
+					spaceblobcap.Get,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[spaceblobcap.GetCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[spaceblobcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[spaceblobcap.GetOk, failure.IPLDBuilderFailure](spaceblobcap.GetOk{
+							Blob:  captypes.Blob{Digest: cap.Nb().Digest, Size: 4},
+							Cause: cause,
+						}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		digest := uhelpers.Must(multihash.Sum([]byte("blob"), multihash.SHA2_256, -1))
+
+		getOk, err := c.SpaceBlobGet(testContext(t), space, digest)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, digest, invokedCapabilities[0].Nb().Digest)
+
+		require.Equal(t, digest, getOk.Blob.Digest)
+		require.Equal(t, uint64(4), getOk.Blob.Size)
+		require.Equal(t, cause.String(), getOk.Cause.String())
+	})
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// SpaceBlobList returns a paginated list of blobs stored in a space.
+//
+// Required delegated capability proofs: `space/blob/list`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `params` are caveats required to perform a `space/blob/list` invocation.
+// Set `Cursor` to the `Cursor` of a previous page to fetch the page after it,
+// and `Size` to limit the number of blobs in the page.
+func (c *Client) SpaceBlobList(ctx context.Context, space did.DID, params spaceblobcap.ListCaveats) (spaceblobcap.ListOk, error) {
+	res, _, err := invokeAndExecute[spaceblobcap.ListCaveats, spaceblobcap.ListOk](
+		ctx,
+		c,
+		spaceblobcap.List,
+		space.String(),
+		params,
+		spaceblobcap.ListOkType(),
+	)
+	if err != nil {
+		return spaceblobcap.ListOk{}, fmt.Errorf("invoking and executing `space/blob/list`: %w", err)
+	}
+
+	listOk, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return spaceblobcap.ListOk{}, newFailureError(spaceblobcap.List.Can(), failErr)
+	}
+
+	return listOk, nil
+}
@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	captypes "github.com/storacha/go-libstoracha/capabilities/types"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpaceBlobList(t *testing.T) {
+	t.Run("returns the page of blobs from `space/blob/list`'s receipt", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[spaceblobcap.ListCaveats]{}
+		digest := uhelpers.Must(multihash.Sum([]byte("blob"), multihash.SHA2_256, -1))
+		next := "next-page"
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				spaceblobcap.List.Can(),
+				server.Provide(
+					spaceblobcap.List,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[spaceblobcap.ListCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[spaceblobcap.ListOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[spaceblobcap.ListOk, failure.IPLDBuilderFailure](spaceblobcap.ListOk{
+							Cursor: &next,
+							Size:   1,
+							Results: []spaceblobcap.ListBlobItem{
+								{Blob: captypes.Blob{Digest: digest, Size: 4}},
+							},
+						}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		cursor := "this-page"
+		size := uint64(1)
+
+		listOk, err := c.SpaceBlobList(testContext(t), space, spaceblobcap.ListCaveats{Cursor: &cursor, Size: &size})
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, cursor, *invokedCapabilities[0].Nb().Cursor)
+		require.Equal(t, size, *invokedCapabilities[0].Nb().Size)
+
+		require.Equal(t, next, *listOk.Cursor)
+		require.Len(t, listOk.Results, 1)
+		require.Equal(t, digest, listOk.Results[0].Blob.Digest)
+		require.Equal(t, uint64(4), listOk.Results[0].Blob.Size)
+	})
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// SpaceBlobRemove removes a blob from a space, freeing the storage it counted
+// against. Any upload whose shards include the blob will no longer be
+// retrievable in full.
+//
+// Required delegated capability proofs: `space/blob/remove`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `digest` is the multihash of the blob.
+//
+// Returns the size of the removed blob, which is zero if the space didn't hold
+// it.
+func (c *Client) SpaceBlobRemove(ctx context.Context, space did.DID, digest multihash.Multihash) (spaceblobcap.RemoveOk, error) {
+	res, _, err := invokeAndExecute[spaceblobcap.RemoveCaveats, spaceblobcap.RemoveOk](
+		ctx,
+		c,
+		spaceblobcap.Remove,
+		space.String(),
+		spaceblobcap.RemoveCaveats{
+			Digest: digest,
+		},
+		spaceblobcap.RemoveOkType(),
+	)
+	if err != nil {
+		return spaceblobcap.RemoveOk{}, fmt.Errorf("invoking and executing `space/blob/remove`: %w", err)
+	}
+
+	removeOk, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return spaceblobcap.RemoveOk{}, newFailureError(spaceblobcap.Remove.Can(), failErr)
+	}
+
+	return removeOk, nil
+}
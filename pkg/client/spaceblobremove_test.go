@@ -0,0 +1,56 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/multiformats/go-multihash"
+	spaceblobcap "github.com/storacha/go-libstoracha/capabilities/space/blob"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpaceBlobRemove(t *testing.T) {
+	t.Run("invokes `space/blob/remove`", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[spaceblobcap.RemoveCaveats]{}
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				spaceblobcap.Remove.Can(),
+				server.Provide(
+					spaceblobcap.Remove,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[spaceblobcap.RemoveCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[spaceblobcap.RemoveOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[spaceblobcap.RemoveOk, failure.IPLDBuilderFailure](spaceblobcap.RemoveOk{Size: 4}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		digest := uhelpers.Must(multihash.Sum([]byte("blob"), multihash.SHA2_256, -1))
+
+		removeOk, err := c.SpaceBlobRemove(testContext(t), space, digest)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, digest, invokedCapabilities[0].Nb().Digest)
+
+		require.Equal(t, uint64(4), removeOk.Size)
+	})
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	indexcap "github.com/storacha/go-libstoracha/capabilities/space/index"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/guppy/pkg/capabilities/spaceindex"
+)
+
+// SpaceIndexAdd registers an index with the service, so that the content it
+// describes can be found through the network's indexers. The index must
+// already have been stored in the space with [Client.SpaceBlobAdd].
+//
+// Required delegated capability proofs: `space/index/add`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `index` is the link to the CAR containing the index.
+func (c *Client) SpaceIndexAdd(ctx context.Context, space did.DID, index ipld.Link) error {
+	res, _, err := invokeAndExecute[indexcap.AddCaveats, indexcap.AddOk](
+		ctx,
+		c,
+		spaceindex.Add,
+		space.String(),
+		indexcap.AddCaveats{
+			Index: index,
+		},
+		indexcap.AddOkType(),
+	)
+	if err != nil {
+		return fmt.Errorf("invoking and executing `space/index/add`: %w", err)
+	}
+
+	_, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return newFailureError(spaceindex.Add.Can(), failErr)
+	}
+
+	return nil
+}
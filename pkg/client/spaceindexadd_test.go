@@ -0,0 +1,54 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	indexcap "github.com/storacha/go-libstoracha/capabilities/space/index"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/capabilities/spaceindex"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpaceIndexAdd(t *testing.T) {
+	t.Run("invokes `space/index/add`", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[indexcap.AddCaveats]{}
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				spaceindex.Add.Can(),
+				server.Provide(
+					spaceindex.Add,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[indexcap.AddCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[indexcap.AddOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[indexcap.AddOk, failure.IPLDBuilderFailure](indexcap.AddOk{}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		index := uhelpers.RandomCID()
+
+		err := c.SpaceIndexAdd(testContext(t), space, index)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, index.String(), invokedCapabilities[0].Nb().Index.String())
+	})
+}
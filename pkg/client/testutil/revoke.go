@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/ucan"
+	ucancap "github.com/storacha/guppy/pkg/capabilities/ucan"
+)
+
+// UCANRevokeHandler returns a handler for `ucan/revoke` which, like the
+// service, accepts the revocation of a delegation sent with the invocation and
+// issued by the invocation's resource. It calls `revoked` with each delegation
+// it revokes.
+func UCANRevokeHandler(revoked func(del delegation.Delegation)) server.HandlerFunc[ucancap.RevokeCaveats, ucancap.RevokeOk, failure.IPLDBuilderFailure] {
+	return func(
+		ctx context.Context,
+		cap ucan.Capability[ucancap.RevokeCaveats],
+		inv invocation.Invocation,
+		context server.InvocationContext,
+	) (result.Result[ucancap.RevokeOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+		br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(inv.Blocks()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading invocation blocks: %w", err)
+		}
+
+		del, err := delegation.NewDelegationView(cap.Nb().Ucan, br)
+		if err != nil {
+			return revokeFailure("UCANNotFound", fmt.Sprintf("delegation %s was not included", cap.Nb().Ucan)), nil, nil
+		}
+
+		if del.Issuer().DID().String() != cap.With() {
+			return revokeFailure("Unauthorized", fmt.Sprintf("%s did not issue delegation %s", cap.With(), del.Link())), nil, nil
+		}
+
+		revoked(del)
+		return result.Ok[ucancap.RevokeOk, failure.IPLDBuilderFailure](ucancap.RevokeOk{Time: time.Now().Unix()}), nil, nil
+	}
+}
+
+func revokeFailure(name string, message string) result.Result[ucancap.RevokeOk, failure.IPLDBuilderFailure] {
+	return result.Error[ucancap.RevokeOk](failure.FromFailureModel(fdm.FailureModel{
+		Name:    &name,
+		Message: message,
+	}))
+}
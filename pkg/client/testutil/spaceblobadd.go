@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/ipld/go-ipld-prime/datamodel"
@@ -317,6 +318,7 @@ func SpaceBlobAddHandler(rcptIssued func(rcpt receipt.AnyReceipt)) (server.Handl
 // receiptsTransport is an [http.RoundTripper] (an [http.Client] transport) that
 // serves known receipts directly rather than using the network.
 type receiptsTransport struct {
+	mu       sync.Mutex
 	receipts map[string]receipt.AnyReceipt
 }
 
@@ -325,7 +327,9 @@ var _ http.RoundTripper = (*receiptsTransport)(nil)
 func (r *receiptsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	path := req.URL.Path
 	invCid := path[10:]
+	r.mu.Lock()
 	rcpt, ok := r.receipts[invCid]
+	r.mu.Unlock()
 	if !ok {
 		return nil, fmt.Errorf("no receipt for invocation %s", invCid)
 	}
@@ -348,19 +352,22 @@ func (r *receiptsTransport) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 // SpaceBlobAddClient creates an entire [client.Client] that's configured to
-// test [spaceblobcap.Add] invocations.
-func SpaceBlobAddClient() (*client.Client, error) {
+// test [spaceblobcap.Add] invocations. Additional `server.Option`s can provide
+// further service methods.
+func SpaceBlobAddClient(options ...server.Option) (*client.Client, error) {
 	receiptsTrans := receiptsTransport{
 		receipts: make(map[string]receipt.AnyReceipt),
 	}
 
-	connection := NewTestServerConnection(
+	connection := NewTestServerConnection(append([]server.Option{
 		server.WithServiceMethod(
 			spaceblobcap.Add.Can(),
 			server.Provide(
 				spaceblobcap.Add,
 				uhelpers.Must(SpaceBlobAddHandler(
 					func(rcpt receipt.AnyReceipt) {
+						receiptsTrans.mu.Lock()
+						defer receiptsTrans.mu.Unlock()
 						receiptsTrans.receipts[rcpt.Ran().Root().Link().String()] = rcpt
 					},
 				)),
@@ -380,7 +387,7 @@ func SpaceBlobAddClient() (*client.Client, error) {
 				},
 			),
 		),
-	)
+	}, options...)...)
 
 	return client.NewClient(
 		client.WithConnection(connection),
@@ -400,6 +407,7 @@ func SpaceBlobAddClient() (*client.Client, error) {
 // blobPutTransport is an [http.RoundTripper] (an [http.Client] transport) that
 // accepts blob PUTs and remembers what was received.
 type blobPutTransport struct {
+	mu            sync.Mutex
 	receivedBlobs [][]byte
 }
 
@@ -410,7 +418,9 @@ func (r *blobPutTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	if err != nil {
 		return nil, fmt.Errorf("reading blob from request: %w", err)
 	}
+	r.mu.Lock()
 	r.receivedBlobs = append(r.receivedBlobs, blob)
+	r.mu.Unlock()
 
 	return &http.Response{
 		StatusCode: 200,
@@ -422,6 +432,8 @@ func ReceivedBlobs(putClient *http.Client) [][]byte {
 	if !ok {
 		panic("The client isn't tracking PUTs. Create a client with NewPutClient() to use ReceivedBlobs().")
 	}
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
 	return transport.receivedBlobs
 }
 
@@ -41,7 +41,7 @@ func (c *Client) UploadAdd(ctx context.Context, space did.DID, root ipld.Link, s
 
 	addOk, failErr := result.Unwrap(res)
 	if failErr != nil {
-		return uploadcap.AddOk{}, fmt.Errorf("`upload/add` failed: %w", failErr)
+		return uploadcap.AddOk{}, newFailureError(uploadcap.Add.Can(), failErr)
 	}
 
 	return addOk, nil
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// UploadGet returns the upload with the given root in a space, including the
+// shards it is stored in.
+//
+// Required delegated capability proofs: `upload/get`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `root` is the root CID of the upload.
+func (c *Client) UploadGet(ctx context.Context, space did.DID, root ipld.Link) (uploadcap.GetOk, error) {
+	res, _, err := invokeAndExecute[uploadcap.GetCaveats, uploadcap.GetOk](
+		ctx,
+		c,
+		uploadcap.Get,
+		space.String(),
+		uploadcap.GetCaveats{
+			Root: root,
+		},
+		uploadcap.GetOkType(),
+	)
+	if err != nil {
+		return uploadcap.GetOk{}, fmt.Errorf("invoking and executing `upload/get`: %w", err)
+	}
+
+	getOk, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return uploadcap.GetOk{}, newFailureError(uploadcap.Get.Can(), failErr)
+	}
+
+	return getOk, nil
+}
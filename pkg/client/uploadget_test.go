@@ -0,0 +1,92 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadGet(t *testing.T) {
+	t.Run("returns the upload from `upload/get`'s receipt", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[uploadcap.GetCaveats]{}
+		shards := []ipld.Link{uhelpers.RandomCID(), uhelpers.RandomCID()}
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				uploadcap.Get.Can(),
+				server.Provide(
+					uploadcap.Get,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[uploadcap.GetCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[uploadcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[uploadcap.GetOk, failure.IPLDBuilderFailure](uploadcap.GetOk{
+							Root:   cap.Nb().Root,
+							Shards: shards,
+						}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		root := uhelpers.RandomCID()
+
+		getOk, err := c.UploadGet(testContext(t), space, root)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, root.String(), invokedCapabilities[0].Nb().Root.String())
+
+		require.Equal(t, root.String(), getOk.Root.String())
+		require.Len(t, getOk.Shards, 2)
+		require.Equal(t, shards[0].String(), getOk.Shards[0].String())
+		require.Equal(t, shards[1].String(), getOk.Shards[1].String())
+	})
+
+	t.Run("returns a not found error for a missing upload", func(t *testing.T) {
+		name := "UploadNotFound"
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				uploadcap.Get.Can(),
+				server.Provide(
+					uploadcap.Get,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[uploadcap.GetCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[uploadcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						return result.Error[uploadcap.GetOk](failure.FromFailureModel(fdm.FailureModel{
+							Name:    &name,
+							Message: "upload not found",
+						})), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+
+		_, err := c.UploadGet(testContext(t), c.Issuer().DID(), uhelpers.RandomCID())
+		require.ErrorIs(t, err, client.ErrNotFound)
+	})
+}
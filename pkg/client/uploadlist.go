@@ -36,7 +36,7 @@ func (c *Client) UploadList(ctx context.Context, space did.DID, params uploadcap
 
 	addOk, failErr := result.Unwrap(res)
 	if failErr != nil {
-		return uploadcap.ListOk{}, fmt.Errorf("`upload/add` failed: %w", failErr)
+		return uploadcap.ListOk{}, newFailureError(uploadcap.List.Can(), failErr)
 	}
 
 	return addOk, nil
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+)
+
+// UploadRemove unregisters the upload with the given root from a space. The
+// shards of the upload are not removed from the space.
+//
+// Required delegated capability proofs: `upload/remove`
+//
+// The `space` is the resource the invocation applies to. It is typically the
+// DID of a space.
+//
+// The `root` is the root CID of the upload.
+//
+// Returns the removed upload, including the shards it was stored in.
+func (c *Client) UploadRemove(ctx context.Context, space did.DID, root ipld.Link) (uploadcap.RemoveOk, error) {
+	res, _, err := invokeAndExecute[uploadcap.RemoveCaveats, uploadcap.RemoveOk](
+		ctx,
+		c,
+		uploadcap.Remove,
+		space.String(),
+		uploadcap.RemoveCaveats{
+			Root: root,
+		},
+		uploadcap.RemoveOkType(),
+	)
+	if err != nil {
+		return uploadcap.RemoveOk{}, fmt.Errorf("invoking and executing `upload/remove`: %w", err)
+	}
+
+	removeOk, failErr := result.Unwrap(res)
+	if failErr != nil {
+		return uploadcap.RemoveOk{}, newFailureError(uploadcap.Remove.Can(), failErr)
+	}
+
+	return removeOk, nil
+}
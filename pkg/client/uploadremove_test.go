@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadRemove(t *testing.T) {
+	t.Run("invokes `upload/remove`", func(t *testing.T) {
+		invokedCapabilities := []ucan.Capability[uploadcap.RemoveCaveats]{}
+		shards := []ipld.Link{uhelpers.RandomCID()}
+
+		connection := testutil.NewTestServerConnection(
+			server.WithServiceMethod(
+				uploadcap.Remove.Can(),
+				server.Provide(
+					uploadcap.Remove,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[uploadcap.RemoveCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[uploadcap.RemoveOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						invokedCapabilities = append(invokedCapabilities, cap)
+						return result.Ok[uploadcap.RemoveOk, failure.IPLDBuilderFailure](uploadcap.RemoveOk{
+							Root:   cap.Nb().Root,
+							Shards: shards,
+						}), nil, nil
+					},
+				),
+			),
+		)
+
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+		space := c.Issuer().DID()
+		root := uhelpers.RandomCID()
+
+		removeOk, err := c.UploadRemove(testContext(t), space, root)
+		require.NoError(t, err)
+
+		require.Len(t, invokedCapabilities, 1, "expected exactly one capability to be invoked")
+		require.Equal(t, space.String(), invokedCapabilities[0].With())
+		require.Equal(t, root.String(), invokedCapabilities[0].Nb().Root.String())
+
+		require.Equal(t, root.String(), removeOk.Root.String())
+		require.Len(t, removeOk.Shards, 1)
+		require.Equal(t, shards[0].String(), removeOk.Shards[0].String())
+	})
+}
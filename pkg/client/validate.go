@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/principal/ed25519/verifier"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/ucan/crypto/signature"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// CheckAccess reports whether the client's proofs authorize its agent to
+// invoke `ability` on `resource`, by validating an invocation locally, as with
+// [WithLocalValidation], without sending it. Caveats aren't checked: the
+// invocation claims the ability without any.
+//
+// It fails with [ErrNoProof] if the client holds no valid delegation which
+// could authorize the invocation, and with a [FailureError] matching
+// [ErrUnauthorized] if the delegations it holds fail validation.
+func (c *Client) CheckAccess(ctx context.Context, ability string, resource string) error {
+	capParser := validator.NewCapability(ability, schema.DIDString(), anyCaveats{}, nil)
+
+	proofs, err := c.selectProofs(ability, resource, time.Now())
+	if err != nil {
+		return err
+	}
+	pfs := make([]delegation.Proof, 0, len(proofs))
+	for _, del := range proofs {
+		pfs = append(pfs, delegation.FromDelegation(del))
+	}
+
+	inv, err := capParser.Invoke(c.Issuer(), c.Connection().ID(), resource, ucan.NoCaveats{}, delegation.WithProof(pfs...))
+	if err != nil {
+		return fmt.Errorf("generating invocation: %w", err)
+	}
+
+	return validateLocally(ctx, c, capParser, inv)
+}
+
+// validateLocally runs the UCAN validator on the invocation and its proofs, as
+// the service would.
+//
+// The client doesn't generally know the key of the service, which is usually
+// identified by a `did:web:`, so unless the service's DID is a `did:key:`,
+// signatures by the service, such as those on the attestations which vouch for
+// delegations from accounts, are trusted rather than verified. The service
+// verifies them itself.
+func validateLocally[Caveats any](ctx context.Context, c *Client, capParser validator.CapabilityParser[Caveats], inv invocation.Invocation) error {
+	vctx := validator.NewValidationContext(
+		serviceAuthority(c.Connection().ID().DID()),
+		capParser,
+		validator.IsSelfIssued[any],
+		func(context.Context, validator.Authorization[any]) validator.Revoked { return nil },
+		c.resolveProof,
+		server.ParsePrincipal,
+		validator.FailDIDKeyResolution,
+	)
+
+	if _, err := validator.Access(ctx, inv, vctx); err != nil {
+		return fmt.Errorf("validating invocation locally: %w", newFailureError(capParser.Can(), err))
+	}
+	return nil
+}
+
+// resolveProof finds a proof which wasn't included with a delegation among the
+// delegations the client holds.
+func (c *Client) resolveProof(ctx context.Context, link ucan.Link) (delegation.Delegation, validator.UnavailableProof) {
+	for _, del := range c.Proofs() {
+		if del.Link().String() == link.String() {
+			return del, nil
+		}
+	}
+	return validator.ProofUnavailable(ctx, link)
+}
+
+// serviceAuthority returns a verifier for the service with the given DID: a
+// real one if the DID is a `did:key:`, and otherwise a [trustedAuthority].
+func serviceAuthority(id did.DID) principal.Verifier {
+	if strings.HasPrefix(id.String(), "did:key:") {
+		if v, err := verifier.Parse(id.String()); err == nil {
+			return v
+		}
+	}
+	return trustedAuthority{id: id}
+}
+
+// trustedAuthority stands in for a service whose key isn't known, accepting
+// any signature as the service's.
+type trustedAuthority struct {
+	id did.DID
+}
+
+func (a trustedAuthority) DID() did.DID {
+	return a.id
+}
+
+func (a trustedAuthority) Code() uint64 {
+	return 0
+}
+
+func (a trustedAuthority) Encode() []byte {
+	return a.id.Bytes()
+}
+
+func (a trustedAuthority) Raw() []byte {
+	return nil
+}
+
+func (a trustedAuthority) Verify(msg []byte, sig signature.Signature) bool {
+	return true
+}
+
+// anyCaveats reads any caveats as [ucan.NoCaveats], for checking an ability
+// without regard to its caveats.
+type anyCaveats struct{}
+
+func (anyCaveats) Read(input any) (ucan.NoCaveats, failure.Failure) {
+	return ucan.NoCaveats{}, nil
+}
@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/server"
+	uhelpers "github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/client"
+	"github.com/storacha/guppy/pkg/client/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalValidation(t *testing.T) {
+	invoked := 0
+	connection := testutil.NewTestServerConnection(
+		server.WithServiceMethod(
+			uploadcap.Get.Can(),
+			server.Provide(
+				uploadcap.Get,
+				func(
+					ctx context.Context,
+					cap ucan.Capability[uploadcap.GetCaveats],
+					inv invocation.Invocation,
+					context server.InvocationContext,
+				) (result.Result[uploadcap.GetOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+					invoked++
+					return result.Ok[uploadcap.GetOk, failure.IPLDBuilderFailure](uploadcap.GetOk{Root: cap.Nb().Root}), nil, nil
+				},
+			),
+		),
+	)
+
+	space := uhelpers.Must(ed25519signer.Generate())
+	caps := func(ability string) []ucan.Capability[ucan.NoCaveats] {
+		return []ucan.Capability[ucan.NoCaveats]{ucan.NewCapability(ability, space.DID().String(), ucan.NoCaveats{})}
+	}
+
+	t.Run("sends an invocation whose proofs are valid", func(t *testing.T) {
+		invoked = 0
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection), client.WithLocalValidation()))
+		require.NoError(t, c.AddProofs(uhelpers.Must(delegation.Delegate(space, c.Issuer(), caps("upload/*"), delegation.WithNoExpiration()))))
+
+		_, err := c.UploadGet(testContext(t), space.DID(), uhelpers.RandomCID())
+		require.NoError(t, err)
+		require.Equal(t, 1, invoked)
+
+		require.NoError(t, c.CheckAccess(testContext(t), "upload/add", space.DID().String()))
+	})
+
+	t.Run("doesn't send an invocation whose proof chain is broken", func(t *testing.T) {
+		invoked = 0
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection), client.WithLocalValidation()))
+
+		// The space delegates to one agent, but a different one re-delegates to
+		// the client, citing it as proof.
+		intended := uhelpers.Must(ed25519signer.Generate())
+		other := uhelpers.Must(ed25519signer.Generate())
+		spaceDel := uhelpers.Must(delegation.Delegate(space, intended, caps("upload/*"), delegation.WithNoExpiration()))
+		del := uhelpers.Must(delegation.Delegate(
+			other,
+			c.Issuer(),
+			caps("upload/*"),
+			delegation.WithNoExpiration(),
+			delegation.WithProof(delegation.FromDelegation(spaceDel)),
+		))
+		require.NoError(t, c.AddProofs(del))
+
+		_, err := c.UploadGet(testContext(t), space.DID(), uhelpers.RandomCID())
+		require.ErrorIs(t, err, client.ErrUnauthorized)
+		require.ErrorContains(t, err, "validating invocation locally")
+		require.ErrorContains(t, err, intended.DID().String(), "expected the error to name the misaligned delegation's audience")
+		require.Equal(t, 0, invoked, "expected nothing to be sent to the service")
+
+		err = c.CheckAccess(testContext(t), "upload/add", space.DID().String())
+		require.ErrorIs(t, err, client.ErrUnauthorized)
+	})
+
+	t.Run("reports a missing proof", func(t *testing.T) {
+		c := uhelpers.Must(client.NewClient(client.WithConnection(connection)))
+
+		err := c.CheckAccess(testContext(t), "upload/add", space.DID().String())
+		require.ErrorIs(t, err, client.ErrNoProof)
+	})
+}
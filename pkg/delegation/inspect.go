@@ -0,0 +1,100 @@
+package delegation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/guppy/pkg/client/nodevalue"
+)
+
+// Info describes a delegation, and the chain of proofs it is based on, in a
+// form suitable for printing or encoding as JSON.
+type Info struct {
+	// CID is the CID of the delegation.
+	CID string `json:"cid"`
+	// Missing is true if the delegation is a proof which wasn't included with
+	// the delegation referring to it, and so only its CID is known.
+	Missing      bool             `json:"missing,omitempty"`
+	Issuer       string           `json:"issuer,omitempty"`
+	Audience     string           `json:"audience,omitempty"`
+	Capabilities []CapabilityInfo `json:"capabilities,omitempty"`
+	// Expiration is when the delegation expires, or nil if it never does.
+	Expiration *time.Time `json:"expiration,omitempty"`
+	// NotBefore is when the delegation becomes valid, or nil if it always was.
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	// Expired is true if the delegation had expired when it was inspected.
+	Expired bool `json:"expired,omitempty"`
+	// NotYetValid is true if the delegation wasn't valid yet when it was
+	// inspected.
+	NotYetValid bool   `json:"notYetValid,omitempty"`
+	Proofs      []Info `json:"proofs,omitempty"`
+}
+
+// CapabilityInfo describes a capability a delegation grants.
+type CapabilityInfo struct {
+	Can  string `json:"can"`
+	With string `json:"with"`
+	Nb   any    `json:"nb,omitempty"`
+}
+
+// Inspect describes the delegation and, recursively, the proofs included with
+// it, flagging any which had expired or weren't yet valid at `now`.
+func Inspect(del delegation.Delegation, now time.Time) (Info, error) {
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(del.Blocks()))
+	if err != nil {
+		return Info{}, fmt.Errorf("reading delegation blocks: %w", err)
+	}
+	return inspect(del, br, now)
+}
+
+func inspect(del delegation.Delegation, br blockstore.BlockReader, now time.Time) (Info, error) {
+	info := Info{
+		CID:      del.Link().String(),
+		Issuer:   del.Issuer().DID().String(),
+		Audience: del.Audience().DID().String(),
+	}
+
+	for _, cap := range del.Capabilities() {
+		capInfo := CapabilityInfo{Can: cap.Can(), With: cap.With(), Nb: cap.Nb()}
+		if node, ok := cap.Nb().(ipld.Node); ok {
+			nb, err := nodevalue.NodeValue(node)
+			if err != nil {
+				return Info{}, fmt.Errorf("reading caveats of `%s`: %w", cap.Can(), err)
+			}
+			capInfo.Nb = nb
+		}
+		if m, ok := capInfo.Nb.(map[string]any); ok && len(m) == 0 {
+			capInfo.Nb = nil
+		}
+		info.Capabilities = append(info.Capabilities, capInfo)
+	}
+
+	if exp := del.Expiration(); exp != nil {
+		t := time.Unix(int64(*exp), 0).UTC()
+		info.Expiration = &t
+		info.Expired = !now.Before(t)
+	}
+	if nbf := del.NotBefore(); nbf != 0 {
+		t := time.Unix(int64(nbf), 0).UTC()
+		info.NotBefore = &t
+		info.NotYetValid = now.Before(t)
+	}
+
+	for _, link := range del.Proofs() {
+		proof, err := delegation.NewDelegationView(link, br)
+		if err != nil {
+			info.Proofs = append(info.Proofs, Info{CID: link.String(), Missing: true})
+			continue
+		}
+		proofInfo, err := inspect(proof, br, now)
+		if err != nil {
+			return Info{}, fmt.Errorf("inspecting proof %s: %w", link, err)
+		}
+		info.Proofs = append(info.Proofs, proofInfo)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,61 @@
+package delegation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	ed25519signer "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/ucan"
+	cdg "github.com/storacha/guppy/pkg/delegation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	space, err := ed25519signer.Generate()
+	require.NoError(t, err)
+	agent, err := ed25519signer.Generate()
+	require.NoError(t, err)
+	audience, err := ed25519signer.Generate()
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	spaceDel, err := delegation.Delegate(
+		space,
+		agent,
+		[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/*", space.DID().String(), ucan.NoCaveats{})},
+		delegation.WithExpiration(int(now.Add(-time.Hour).Unix())),
+	)
+	require.NoError(t, err)
+
+	del, err := delegation.Delegate(
+		agent,
+		audience,
+		[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", space.DID().String(), ucan.NoCaveats{})},
+		delegation.WithNoExpiration(),
+		delegation.WithNotBefore(int(now.Add(time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(spaceDel)),
+	)
+	require.NoError(t, err)
+
+	info, err := cdg.Inspect(del, now)
+	require.NoError(t, err)
+
+	require.Equal(t, del.Link().String(), info.CID)
+	require.Equal(t, agent.DID().String(), info.Issuer)
+	require.Equal(t, audience.DID().String(), info.Audience)
+	require.Equal(t, []cdg.CapabilityInfo{{Can: "upload/add", With: space.DID().String()}}, info.Capabilities)
+	require.Nil(t, info.Expiration)
+	require.False(t, info.Expired)
+	require.NotNil(t, info.NotBefore)
+	require.True(t, info.NotYetValid)
+
+	require.Len(t, info.Proofs, 1)
+	proof := info.Proofs[0]
+	require.Equal(t, spaceDel.Link().String(), proof.CID)
+	require.False(t, proof.Missing)
+	require.Equal(t, space.DID().String(), proof.Issuer)
+	require.True(t, proof.Expired)
+	require.False(t, proof.NotYetValid)
+}
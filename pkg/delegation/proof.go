@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/storacha/go-ucanto/core/car"
 	"github.com/storacha/go-ucanto/core/dag/blockstore"
@@ -15,11 +16,16 @@ import (
 // with fallback to extracting from the legacy encoding.
 //
 // It will first attempt to extract using `delegation.Extract` from
-// `go-ucanto/core/delegation` and falls back to decoding by reading a plain
+// `go-ucanto/core/delegation`, then to parse the base64 encoded form written by
+// `delegation.Format`, and finally falls back to decoding by reading a plain
 // CAR file, assuming the last block is the delegation root.
 func ExtractProof(b []byte) (delegation.Delegation, error) {
 	proof, err := delegation.Extract(b)
 	if err != nil {
+		if proof, err := delegation.Parse(strings.TrimSpace(string(b))); err == nil {
+			return proof, nil
+		}
+
 		// try decode legacy format
 		_, blocks, err := car.Decode(bytes.NewReader(b))
 		if err != nil {
@@ -15,6 +15,7 @@ import (
 	"github.com/storacha/guppy/pkg/preparation/dags/visitor"
 	"github.com/storacha/guppy/pkg/preparation/types/id"
 	"github.com/storacha/guppy/pkg/preparation/uploads"
+	"golang.org/x/sync/errgroup"
 )
 
 const BlockSize = 1 << 20         // 1 MiB
@@ -59,8 +60,23 @@ func (a API) RestartDagScansForUpload(ctx context.Context, uploadID id.UploadID)
 	return nil
 }
 
-// RunDagScansForUpload runs all pending and awaiting children DAG scans for the given upload, until there are no more scans to process.
-func (a API) RunDagScansForUpload(ctx context.Context, uploadID id.UploadID, nodeCB func(node model.Node, data []byte) error) error {
+// RunDagScansForUpload runs all pending and awaiting children DAG scans for the
+// given upload, until there are no more scans to process. Up to `concurrency`
+// scans run at once. However many run at once, `nodeCB` is called for one node
+// at a time, in the same order as if the scans had run one after another, and a
+// scan is only recorded as completed once `nodeCB` has been called for all of
+// its nodes.
+func (a API) RunDagScansForUpload(ctx context.Context, uploadID id.UploadID, concurrency int, nodeCB func(node model.Node) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// A scan holds its slot until all of its nodes have been delivered, which
+	// also bounds how many scans' nodes can be held waiting for earlier scans.
+	slots := make(chan struct{}, concurrency)
+	sequencer := newNodeSequencer(nodeCB, func() { <-slots })
+	nodeRepo := newRunNodeRepo(a.Repo)
+
 	for {
 		dagScans, err := a.Repo.DAGScansForUploadByStatus(ctx, uploadID, model.DAGScanStatePending, model.DAGScanStateAwaitingChildren)
 		if err != nil {
@@ -70,15 +86,12 @@ func (a API) RunDagScansForUpload(ctx context.Context, uploadID id.UploadID, nod
 		if len(dagScans) == 0 {
 			return nil // No pending or awaiting children scans found, exit the loop
 		}
-		executions := 0
+
+		runnable := make([]model.DAGScan, 0, len(dagScans))
 		for _, dagScan := range dagScans {
 			switch dagScan.State() {
 			case model.DAGScanStatePending:
-				log.Debugf("Executing dag scan %s in state %s", dagScan.FsEntryID(), dagScan.State())
-				if err := a.ExecuteDAGScan(ctx, dagScan, nodeCB); err != nil {
-					return fmt.Errorf("executing dag scan %s: %w", dagScan.FsEntryID(), err)
-				}
-				executions++
+				runnable = append(runnable, dagScan)
 			case model.DAGScanStateAwaitingChildren:
 				log.Debugf("Handling awaiting children for dag scan %s in state %s", dagScan.FsEntryID(), dagScan.State())
 				if err := a.HandleAwaitingChildren(ctx, dagScan); err != nil {
@@ -86,69 +99,134 @@ func (a API) RunDagScansForUpload(ctx context.Context, uploadID id.UploadID, nod
 				}
 				// if the scan is now in a state where it can be executed, execute it
 				if dagScan.State() == model.DAGScanStatePending {
-					if err := a.ExecuteDAGScan(ctx, dagScan, nodeCB); err != nil {
-						return fmt.Errorf("executing dag scan %s after handling awaiting children: %w", dagScan.FsEntryID(), err)
-					}
-					executions++
+					runnable = append(runnable, dagScan)
 				}
 			default:
 				return fmt.Errorf("unexpected dag scan state %s for scan %s", dagScan.State(), dagScan.FsEntryID())
 			}
 		}
-		if executions == 0 {
+		if len(runnable) == 0 {
 			return nil // No scans executed, only awaiting children handled and no pending scans left
 		}
+
+		if err := a.executeDAGScans(ctx, runnable, slots, sequencer, nodeRepo); err != nil {
+			return err
+		}
+	}
+}
+
+// executeDAGScans executes the given scans, as many at once as there are
+// slots, and waits for them all to finish.
+func (a API) executeDAGScans(ctx context.Context, dagScans []model.DAGScan, slots chan<- struct{}, sequencer *nodeSequencer, nodeRepo visitor.Repo) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+scans:
+	for _, dagScan := range dagScans {
+		select {
+		case slots <- struct{}{}:
+		case <-egCtx.Done():
+			break scans
+		}
+
+		i, scanNodeCB := sequencer.add()
+		eg.Go(func() error {
+			log.Debugf("Executing dag scan %s in state %s", dagScan.FsEntryID(), dagScan.State())
+			scanCID, err := a.runDAGScan(egCtx, nodeRepo, dagScan, scanNodeCB)
+			if err != nil {
+				return fmt.Errorf("executing dag scan %s: %w", dagScan.FsEntryID(), err)
+			}
+			// The sequencer only completes the scan once its nodes have all been
+			// handed on, which may be after later scans have finished.
+			complete := func() error {
+				if scanCID == cid.Undef {
+					return nil
+				}
+				if err := a.completeDAGScan(egCtx, dagScan, scanCID); err != nil {
+					return fmt.Errorf("completing dag scan %s: %w", dagScan.FsEntryID(), err)
+				}
+				return nil
+			}
+			if err := sequencer.finish(i, complete); err != nil {
+				return fmt.Errorf("handling nodes of dag scan %s: %w", dagScan.FsEntryID(), err)
+			}
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
 	}
+	return ctx.Err()
 }
 
 // ExecuteDAGScan executes a dag scan on the given fs entry, creating a unix fs dag for the given file or directory.
 func (a API) ExecuteDAGScan(ctx context.Context, dagScan model.DAGScan, nodeCB func(node model.Node, data []byte) error) error {
+	scanCID, err := a.runDAGScan(ctx, a.Repo, dagScan, nodeCB)
+	if err != nil || !scanCID.Defined() {
+		return err
+	}
+	return a.completeDAGScan(ctx, dagScan, scanCID)
+}
+
+// runDAGScan starts and executes a dag scan, with its nodes found or created in
+// `nodeRepo`. If the scan fails or is canceled, that's recorded, and it returns
+// [cid.Undef]. Otherwise, it returns the scan's CID, and leaves it to the caller
+// to complete the scan.
+func (a API) runDAGScan(ctx context.Context, nodeRepo visitor.Repo, dagScan model.DAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
 	err := dagScan.Start()
 	if err != nil {
 		log.Debug("Failed to start dag scan:", err)
-		return fmt.Errorf("starting dag scan: %w", err)
+		return cid.Undef, fmt.Errorf("starting dag scan: %w", err)
 	}
 	if err := a.Repo.UpdateDAGScan(ctx, dagScan); err != nil {
 		log.Debugf("Failed to update dag scan %s: %v", dagScan.FsEntryID(), err)
-		return fmt.Errorf("updating dag scan: %w", err)
+		return cid.Undef, fmt.Errorf("updating dag scan: %w", err)
 	}
-	cid, err := a.executeDAGScan(ctx, dagScan, nodeCB)
-	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			if err := dagScan.Cancel(); err != nil {
-				return fmt.Errorf("canceling dag scan: %w", err)
-			}
-		} else {
-			if err := dagScan.Fail(err.Error()); err != nil {
-				return fmt.Errorf("failing dag scan: %w", err)
-			}
+	scanCID, err := a.executeDAGScan(ctx, nodeRepo, dagScan, nodeCB)
+	if err == nil {
+		return scanCID, nil
+	}
+	if errors.Is(err, context.Canceled) {
+		if err := dagScan.Cancel(); err != nil {
+			return cid.Undef, fmt.Errorf("canceling dag scan: %w", err)
 		}
 	} else {
-		log.Debugf("Completing DAG scan for %s with CID:", dagScan.FsEntryID(), cid)
-		if err := dagScan.Complete(cid); err != nil {
-			return fmt.Errorf("completing dag scan: %w", err)
+		if err := dagScan.Fail(err.Error()); err != nil {
+			return cid.Undef, fmt.Errorf("failing dag scan: %w", err)
 		}
 	}
-	// Update the scan in the repository after completion or failure.
+	// Update the scan in the repository after failure.
 	log.Debugf("Updating dag scan %s after execution", dagScan.FsEntryID())
 	if err := a.Repo.UpdateDAGScan(ctx, dagScan); err != nil {
-		return fmt.Errorf("updating dag scan after fail: %w", err)
+		return cid.Undef, fmt.Errorf("updating dag scan after fail: %w", err)
+	}
+	return cid.Undef, nil
+}
+
+// completeDAGScan records a dag scan as completed with the given CID.
+func (a API) completeDAGScan(ctx context.Context, dagScan model.DAGScan, scanCID cid.Cid) error {
+	log.Debugf("Completing DAG scan for %s with CID: %s", dagScan.FsEntryID(), scanCID)
+	if err := dagScan.Complete(scanCID); err != nil {
+		return fmt.Errorf("completing dag scan: %w", err)
+	}
+	if err := a.Repo.UpdateDAGScan(ctx, dagScan); err != nil {
+		return fmt.Errorf("updating dag scan after completion: %w", err)
 	}
 	return nil
 }
 
-func (a API) executeDAGScan(ctx context.Context, dagScan model.DAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
+func (a API) executeDAGScan(ctx context.Context, nodeRepo visitor.Repo, dagScan model.DAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
 	switch ds := dagScan.(type) {
 	case *model.FileDAGScan:
-		return a.executeFileDAGScan(ctx, ds, nodeCB)
+		return a.executeFileDAGScan(ctx, nodeRepo, ds, nodeCB)
 	case *model.DirectoryDAGScan:
-		return a.executeDirectoryDAGScan(ctx, ds, nodeCB)
+		return a.executeDirectoryDAGScan(ctx, nodeRepo, ds, nodeCB)
 	default:
 		return cid.Undef, fmt.Errorf("unrecognized DAG scan type: %T", dagScan)
 	}
 }
 
-func (a API) executeFileDAGScan(ctx context.Context, dagScan *model.FileDAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
+func (a API) executeFileDAGScan(ctx context.Context, nodeRepo visitor.Repo, dagScan *model.FileDAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
 	log.Debugf("Executing file DAG scan for fsEntryID %s", dagScan.FsEntryID())
 	f, sourceID, path, err := a.FileAccessor(ctx, dagScan.FsEntryID())
 	if err != nil {
@@ -156,7 +234,7 @@ func (a API) executeFileDAGScan(ctx context.Context, dagScan *model.FileDAGScan,
 	}
 	defer f.Close()
 	reader := visitor.ReaderPositionFromReader(f)
-	visitor := visitor.NewUnixFSFileNodeVisitor(ctx, a.Repo, sourceID, path, reader, nodeCB)
+	visitor := visitor.NewUnixFSFileNodeVisitor(ctx, nodeRepo, sourceID, path, reader, nodeCB)
 	log.Debugf("Building UnixFS file with source ID %s and path %s", sourceID, path)
 	l, _, err := builder.BuildUnixFSFile(reader, fmt.Sprintf("size-%d", BlockSize), visitor.LinkSystem())
 	if err != nil {
@@ -166,22 +244,25 @@ func (a API) executeFileDAGScan(ctx context.Context, dagScan *model.FileDAGScan,
 	return l.(cidlink.Link).Cid, nil
 }
 
-func (a API) executeDirectoryDAGScan(ctx context.Context, dagScan *model.DirectoryDAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
+func (a API) executeDirectoryDAGScan(ctx context.Context, nodeRepo visitor.Repo, dagScan *model.DirectoryDAGScan, nodeCB func(node model.Node, data []byte) error) (cid.Cid, error) {
 	log.Debugf("Executing directory DAG scan for fsEntryID %s", dagScan.FsEntryID())
 	childLinks, err := a.Repo.DirectoryLinks(ctx, dagScan)
 	if err != nil {
 		return cid.Undef, fmt.Errorf("getting directory links for DAG scan: %w", err)
 	}
 	log.Debugf("Found %d child links for directory scan %s", len(childLinks), dagScan.FsEntryID())
-	visitor := visitor.NewUnixFSDirectoryNodeVisitor(ctx, a.Repo, nodeCB)
+	visitor := visitor.NewUnixFSDirectoryNodeVisitor(ctx, nodeRepo, nodeCB)
 	pbLinks, err := toLinks(childLinks)
 	if err != nil {
 		return cid.Undef, fmt.Errorf("converting links to PBLinks: %w", err)
 	}
 	log.Debugf("Building UnixFS directory with %d links", len(pbLinks))
 	l, _, err := builder.BuildUnixFSDirectory(pbLinks, visitor.LinkSystem())
+	if err != nil {
+		return cid.Undef, fmt.Errorf("building UnixFS directory: %w", err)
+	}
 	log.Debugf("Built UnixFS directory with CID: %s", l.(cidlink.Link).Cid)
-	return l.(cidlink.Link).Cid, err
+	return l.(cidlink.Link).Cid, nil
 }
 
 // HandleAwaitingChildren checks if all child scans of a directory scan are completed and marks the parent scan pending if so.
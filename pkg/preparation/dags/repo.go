@@ -14,6 +14,9 @@ type Repo interface {
 	FindOrCreateRawNode(ctx context.Context, cid cid.Cid, size uint64, path string, sourceID id.SourceID, offset uint64) (*model.RawNode, bool, error)
 	FindOrCreateUnixFSNode(ctx context.Context, cid cid.Cid, size uint64, ufsdata []byte) (*model.UnixFSNode, bool, error)
 	CreateLinks(ctx context.Context, parent cid.Cid, links []model.LinkParams) error
+	// NodeInShard reports whether the node has been added to a shard of any
+	// upload.
+	NodeInShard(ctx context.Context, cid cid.Cid) (bool, error)
 	LinksForCID(ctx context.Context, cid cid.Cid) ([]*model.Link, error)
 	GetChildScans(ctx context.Context, directoryScans *model.DirectoryDAGScan) ([]model.DAGScan, error)
 	DAGScansForUploadByStatus(ctx context.Context, uploadID id.UploadID, states ...model.DAGScanState) ([]model.DAGScan, error)
@@ -0,0 +1,179 @@
+package dags
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/storacha/guppy/pkg/preparation/dags/model"
+	"github.com/storacha/guppy/pkg/preparation/dags/visitor"
+	"github.com/storacha/guppy/pkg/preparation/types/id"
+)
+
+// nodeSequencer delivers the nodes of concurrently executing DAG scans to a
+// callback in the order the scans were started, one at a time, so that nodes
+// are handed on in the same order as if the scans had run one after another.
+//
+// Nodes from the earliest unfinished scan go straight to the callback. Nodes
+// from later scans are held until every earlier scan has finished. Only the
+// nodes themselves are held, not their data, which the callback can read back
+// from the repo if it needs it.
+//
+// A scan isn't recorded as completed until all of its nodes have been
+// delivered, so if the run stops before then, the scan runs again next time.
+type nodeSequencer struct {
+	mu     sync.Mutex
+	nodeCB func(node model.Node) error
+	// head is the index of the earliest scan which hasn't finished.
+	head     int
+	held     [][]model.Node
+	finished []bool
+	// complete records each finished scan as completed, once its nodes have
+	// all been delivered.
+	complete []func() error
+	// delivered is the set of nodes already passed to nodeCB, so that a node
+	// produced by more than one scan is only delivered for the first of them.
+	delivered map[cid.Cid]struct{}
+	// release is called as each scan's nodes have all been delivered.
+	release func()
+}
+
+func newNodeSequencer(nodeCB func(node model.Node) error, release func()) *nodeSequencer {
+	return &nodeSequencer{
+		nodeCB:    nodeCB,
+		delivered: make(map[cid.Cid]struct{}),
+		release:   release,
+	}
+}
+
+// add registers a new scan, returning its index and the callback the scan
+// should use for its nodes.
+func (s *nodeSequencer) add() (int, func(node model.Node, data []byte) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := len(s.finished)
+	s.held = append(s.held, nil)
+	s.finished = append(s.finished, false)
+	s.complete = append(s.complete, nil)
+
+	return i, func(node model.Node, data []byte) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if i == s.head {
+			return s.deliver(node)
+		}
+		s.held[i] = append(s.held[i], node)
+		return nil
+	}
+}
+
+// finish marks the scan with the given index as finished. Once every earlier
+// scan has finished too, `complete` is called to record it as completed, and
+// the held nodes of any later scans which are now at the head are delivered.
+func (s *nodeSequencer) finish(i int, complete func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finished[i] = true
+	s.complete[i] = complete
+	for s.head < len(s.finished) && s.finished[s.head] {
+		if err := s.complete[s.head](); err != nil {
+			return err
+		}
+		s.complete[s.head] = nil
+		s.head++
+		s.release()
+
+		if s.head < len(s.held) {
+			held := s.held[s.head]
+			s.held[s.head] = nil
+			for _, node := range held {
+				if err := s.deliver(node); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *nodeSequencer) deliver(node model.Node) error {
+	if _, ok := s.delivered[node.CID()]; ok {
+		return nil
+	}
+	s.delivered[node.CID()] = struct{}{}
+	return s.nodeCB(node)
+}
+
+// runNodeRepo wraps the repo the visitors of a run of DAG scans use. A node is
+// only called back by the scan which creates it, and which concurrent scan
+// gets there first is down to timing, so this reports a node as created to
+// every scan in the run which produces it. The [nodeSequencer] then delivers
+// it for whichever of those scans comes first.
+//
+// It also reports a node as created if it already existed but was never added
+// to a shard, which happens when an earlier run stopped before handing on the
+// nodes of a scan. That scan runs again, and this time its nodes are delivered.
+type runNodeRepo struct {
+	Repo
+	mu      sync.Mutex
+	created map[cid.Cid]struct{}
+}
+
+var _ visitor.Repo = (*runNodeRepo)(nil)
+
+func newRunNodeRepo(repo Repo) *runNodeRepo {
+	return &runNodeRepo{
+		Repo:    repo,
+		created: make(map[cid.Cid]struct{}),
+	}
+}
+
+func (r *runNodeRepo) FindOrCreateRawNode(ctx context.Context, cid cid.Cid, size uint64, path string, sourceID id.SourceID, offset uint64) (*model.RawNode, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node, created, err := r.Repo.FindOrCreateRawNode(ctx, cid, size, path, sourceID, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	created, err = r.createdInRun(ctx, cid, created)
+	if err != nil {
+		return nil, false, err
+	}
+	return node, created, nil
+}
+
+func (r *runNodeRepo) FindOrCreateUnixFSNode(ctx context.Context, cid cid.Cid, size uint64, ufsdata []byte) (*model.UnixFSNode, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node, created, err := r.Repo.FindOrCreateUnixFSNode(ctx, cid, size, ufsdata)
+	if err != nil {
+		return nil, false, err
+	}
+	created, err = r.createdInRun(ctx, cid, created)
+	if err != nil {
+		return nil, false, err
+	}
+	return node, created, nil
+}
+
+func (r *runNodeRepo) createdInRun(ctx context.Context, c cid.Cid, created bool) (bool, error) {
+	if _, ok := r.created[c]; ok {
+		return true, nil
+	}
+	if !created {
+		inShard, err := r.Repo.NodeInShard(ctx, c)
+		if err != nil {
+			return false, err
+		}
+		if inShard {
+			return false, nil
+		}
+	}
+	r.created[c] = struct{}{}
+	return true, nil
+}
@@ -1,7 +1,6 @@
 package preparation
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -15,6 +14,7 @@ import (
 	ipldcar "github.com/ipld/go-car"
 	"github.com/ipld/go-car/util"
 	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/guppy/pkg/client"
 	"github.com/storacha/guppy/pkg/preparation/configurations"
 	configurationsmodel "github.com/storacha/guppy/pkg/preparation/configurations/model"
 	"github.com/storacha/guppy/pkg/preparation/dags"
@@ -42,6 +42,16 @@ type Repo interface {
 	shards.Repo
 }
 
+// Client is the subset of [client.Client] the preparation process uses to talk
+// to the service.
+type Client interface {
+	shards.SpaceBlobAdder
+	shards.UploadAdder
+	shards.IndexAdder
+}
+
+var _ Client = (*client.Client)(nil)
+
 type API struct {
 	Configurations configurations.API
 	Uploads        uploads.API
@@ -55,11 +65,13 @@ type Option func(cfg *config) error
 
 type config struct {
 	getLocalFSForPathFn func(path string) (fs.FS, error)
+	shardParallelism    int
 }
 
-func NewAPI(repo Repo, client shards.SpaceBlobAdder, space did.DID, options ...Option) API {
+func NewAPI(repo Repo, client Client, space did.DID, options ...Option) API {
 	cfg := &config{
 		getLocalFSForPathFn: func(path string) (fs.FS, error) { return os.DirFS(path), nil },
+		shardParallelism:    1,
 	}
 	for _, opt := range options {
 		if err := opt(cfg); err != nil {
@@ -96,27 +108,13 @@ func NewAPI(repo Repo, client shards.SpaceBlobAdder, space did.DID, options ...O
 	}
 
 	shardsAPI := shards.API{
-		Repo:   repo,
-		Client: client,
-		Space:  space,
-		CarForShard: func(ctx context.Context, shard *shardsmodel.Shard) (io.Reader, error) {
-			var buf bytes.Buffer
-
-			header, err := cbor.DumpObject(
-				ipldcar.CarHeader{
-					Roots:   nil,
-					Version: 1,
-				},
-			)
-			if err != nil {
-				return nil, fmt.Errorf("dumping CAR header: %w", err)
-			}
-
-			err = util.LdWrite(&buf, header)
-			if err != nil {
-				return nil, fmt.Errorf("writing CAR header: %w", err)
-			}
-
+		Repo:        repo,
+		Client:      client,
+		UploadAdder: client,
+		IndexAdder:  client,
+		Space:       space,
+		Parallelism: cfg.shardParallelism,
+		CarForShard: func(ctx context.Context, shard *shardsmodel.Shard) (io.ReadCloser, error) {
 			nr, err := dags.NewNodeReader(repo, func(ctx context.Context, sourceID id.SourceID, path string) (fs.File, error) {
 				source, err := repo.GetSourceByID(ctx, sourceID)
 				if err != nil {
@@ -134,25 +132,17 @@ func NewAPI(repo Repo, client shards.SpaceBlobAdder, space did.DID, options ...O
 				}
 				return f, nil
 			}, false)
-
-			err = repo.ForEachNode(ctx, shard.ID(), func(node dagsmodel.Node) error {
-				data, err := nr.GetData(ctx, node)
-				if err != nil {
-					return fmt.Errorf("getting data for node %s: %w", node.CID(), err)
-				}
-
-				err = util.LdWrite(&buf, []byte(node.CID().KeyString()), data)
-				if err != nil {
-					return fmt.Errorf("writing CAR block for CID %s: %w", node.CID(), err)
-				}
-
-				return nil
-			})
 			if err != nil {
-				return nil, fmt.Errorf("failed to iterate over nodes in shard %s: %w", shard.ID(), err)
+				return nil, fmt.Errorf("creating node reader: %w", err)
 			}
 
-			return bytes.NewReader(buf.Bytes()), nil
+			// Write the CAR into a pipe as it's read, so that only one block at a
+			// time is held in memory. Closing the reader early stops the writer.
+			pr, pw := io.Pipe()
+			go func() {
+				pw.CloseWithError(writeShardCAR(ctx, pw, repo, nr, shard))
+			}()
+			return pr, nil
 		},
 	}
 
@@ -189,6 +179,8 @@ func NewAPI(repo Repo, client shards.SpaceBlobAdder, space did.DID, options ...O
 		AddNodeToUploadShards:       shardsAPI.AddNodeToUploadShards,
 		CloseUploadShards:           shardsAPI.CloseUploadShards,
 		SpaceBlobAddShardsForUpload: shardsAPI.SpaceBlobAddShardsForUpload,
+		AddIndexForUpload:           shardsAPI.AddIndexForUpload,
+		RegisterUploadShards:        shardsAPI.RegisterUploadShards,
 	}
 
 	return API{
@@ -207,6 +199,18 @@ func WithGetLocalFSForPathFn(getLocalFSForPathFn func(path string) (fs.FS, error
 	}
 }
 
+// WithShardParallelism sets how many shards may be added to the space at once.
+// The default is 1.
+func WithShardParallelism(parallelism int) Option {
+	return func(cfg *config) error {
+		if parallelism < 1 {
+			return fmt.Errorf("shard parallelism must be at least 1, got %d", parallelism)
+		}
+		cfg.shardParallelism = parallelism
+		return nil
+	}
+}
+
 func (a API) CreateConfiguration(ctx context.Context, name string, options ...configurationsmodel.ConfigurationOption) (*configurationsmodel.Configuration, error) {
 	return a.Configurations.CreateConfiguration(ctx, name, options...)
 }
@@ -219,6 +223,56 @@ func (a API) CreateUploads(ctx context.Context, configurationID id.Configuration
 	return a.Uploads.CreateUploads(ctx, configurationID)
 }
 
-func (a API) ExecuteUpload(ctx context.Context, upload *uploadsmodel.Upload) (cid.Cid, error) {
-	return a.Uploads.ExecuteUpload(ctx, upload)
+func (a API) ExecuteUpload(ctx context.Context, upload *uploadsmodel.Upload, options ...uploads.ExecuteOption) (cid.Cid, error) {
+	return a.Uploads.ExecuteUpload(ctx, upload, options...)
+}
+
+// writeShardCAR writes the CAR for the shard to `w`, reading each block's data
+// from its source as it goes.
+func writeShardCAR(ctx context.Context, w io.Writer, repo Repo, nr *dags.NodeReader, shard *shardsmodel.Shard) error {
+	// Only the shard holding the upload's root node declares a root.
+	var roots []cid.Cid
+	if shard.Root() != cid.Undef {
+		roots = []cid.Cid{shard.Root()}
+	}
+
+	header, err := cbor.DumpObject(
+		ipldcar.CarHeader{
+			Roots:   roots,
+			Version: 1,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("dumping CAR header: %w", err)
+	}
+
+	err = util.LdWrite(w, header)
+	if err != nil {
+		return fmt.Errorf("writing CAR header: %w", err)
+	}
+
+	// Collect the nodes first, rather than holding the query open while reading
+	// and writing block data. The nodes themselves are only metadata.
+	var nodes []dagsmodel.Node
+	err = repo.ForEachNode(ctx, shard.ID(), func(node dagsmodel.Node) error {
+		nodes = append(nodes, node)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate over nodes in shard %s: %w", shard.ID(), err)
+	}
+
+	for _, node := range nodes {
+		data, err := nr.GetData(ctx, node)
+		if err != nil {
+			return fmt.Errorf("getting data for node %s: %w", node.CID(), err)
+		}
+
+		err = util.LdWrite(w, []byte(node.CID().KeyString()), data)
+		if err != nil {
+			return fmt.Errorf("writing CAR block for CID %s: %w", node.CID(), err)
+		}
+	}
+
+	return nil
 }
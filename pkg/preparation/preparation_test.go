@@ -3,6 +3,7 @@ package preparation_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/fs"
 	"math/rand"
@@ -18,19 +19,31 @@ import (
 	"github.com/ipfs/go-cid"
 	format "github.com/ipfs/go-ipld-format"
 	"github.com/ipld/go-car/v2/blockstore"
+	"github.com/multiformats/go-multicodec"
 	"github.com/multiformats/go-multihash"
 	"github.com/spf13/afero"
+	"github.com/storacha/go-libstoracha/blobindex"
+	indexcap "github.com/storacha/go-libstoracha/capabilities/space/index"
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
 	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
 	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/server"
 	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/capabilities/spaceindex"
 	"github.com/storacha/guppy/pkg/client"
 	ctestutil "github.com/storacha/guppy/pkg/client/testutil"
 	"github.com/storacha/guppy/pkg/preparation"
 	configurationsmodel "github.com/storacha/guppy/pkg/preparation/configurations/model"
-	"github.com/storacha/guppy/pkg/preparation/shards"
 	"github.com/storacha/guppy/pkg/preparation/shards/model"
 	"github.com/storacha/guppy/pkg/preparation/sqlrepo"
 	"github.com/storacha/guppy/pkg/preparation/testutil"
+	uploadsapi "github.com/storacha/guppy/pkg/preparation/uploads"
+	uploadsmodel "github.com/storacha/guppy/pkg/preparation/uploads/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -43,14 +56,14 @@ func randomBytes(n int) []byte {
 	return b
 }
 
-// spaceBlobAddClient is a [shards.SpaceBlobAdder] that wraps a [client.Client]
+// spaceBlobAddClient is a [preparation.Client] that wraps a [client.Client]
 // to use a custom putClient.
 type spaceBlobAddClient struct {
 	*client.Client
 	putClient *http.Client
 }
 
-var _ shards.SpaceBlobAdder = (*spaceBlobAddClient)(nil)
+var _ preparation.Client = (*spaceBlobAddClient)(nil)
 
 func (c *spaceBlobAddClient) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.DID, options ...client.SpaceBlobAddOption) (multihash.Multihash, delegation.Delegation, error) {
 	return c.Client.SpaceBlobAdd(ctx, content, space, append(options, client.WithPutClient(c.putClient))...)
@@ -102,6 +115,58 @@ func (c *compositeBlockstore) HashOnRead(enabled bool) {
 	panic("not implemented")
 }
 
+// testService records the invocations handled by the service behind a
+// [newTestClient].
+type testService struct {
+	uploadAdds []uploadcap.AddCaveats
+	indexAdds  []indexcap.AddCaveats
+}
+
+// newTestClient returns a client for a service which accepts blobs, indexes
+// and uploads, recording them in `svc`.
+func newTestClient(t *testing.T, svc *testService, putClient *http.Client) *spaceBlobAddClient {
+	t.Helper()
+
+	return &spaceBlobAddClient{
+		Client: helpers.Must(ctestutil.SpaceBlobAddClient(
+			server.WithServiceMethod(
+				spaceindex.Add.Can(),
+				server.Provide(
+					spaceindex.Add,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[indexcap.AddCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[indexcap.AddOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						svc.indexAdds = append(svc.indexAdds, cap.Nb())
+						return result.Ok[indexcap.AddOk, failure.IPLDBuilderFailure](indexcap.AddOk{}), nil, nil
+					},
+				),
+			),
+			server.WithServiceMethod(
+				uploadcap.Add.Can(),
+				server.Provide(
+					uploadcap.Add,
+					func(
+						ctx context.Context,
+						cap ucan.Capability[uploadcap.AddCaveats],
+						inv invocation.Invocation,
+						context server.InvocationContext,
+					) (result.Result[uploadcap.AddOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+						svc.uploadAdds = append(svc.uploadAdds, cap.Nb())
+						return result.Ok[uploadcap.AddOk, failure.IPLDBuilderFailure](uploadcap.AddOk{
+							Root:   cap.Nb().Root,
+							Shards: cap.Nb().Shards,
+						}), nil, nil
+					},
+				),
+			),
+		)),
+		putClient: putClient,
+	}
+}
+
 func TestExecuteUpload(t *testing.T) {
 	// In case something goes wrong. This should never take this long.
 	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
@@ -129,10 +194,8 @@ func TestExecuteUpload(t *testing.T) {
 
 	putClient := ctestutil.NewPutClient()
 
-	c := &spaceBlobAddClient{
-		Client:    helpers.Must(ctestutil.SpaceBlobAddClient()),
-		putClient: putClient,
-	}
+	svc := &testService{}
+	c := newTestClient(t, svc, putClient)
 
 	// Use the client's issuer as the space DID to avoid any concerns about
 	// authorization.
@@ -174,15 +237,76 @@ func TestExecuteUpload(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, addedShards, 5, "expected all shards to added be for the upload")
 
+	// One blob for each shard, and one for the index.
 	putBlobs := ctestutil.ReceivedBlobs(putClient)
-	require.Len(t, putBlobs, 5, "expected exactly 5 blobs to be added")
+	require.Len(t, putBlobs, 6, "expected exactly 6 blobs to be added")
+
+	require.Len(t, svc.indexAdds, 1, "expected the index to be registered once")
 
-	blobBlockstores := make([]blockstore.Blockstore, 0, len(putBlobs))
+	var indexBlob []byte
+	shardBlobs := make(map[string][]byte, len(putBlobs))
 	for _, blob := range putBlobs {
+		digest, err := multihash.Sum(blob, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+		blobCid := cid.NewCidV1(uint64(multicodec.Car), digest)
+		if blobCid.String() == svc.indexAdds[0].Index.String() {
+			indexBlob = blob
+		} else {
+			shardBlobs[string(digest)] = blob
+		}
+	}
+	require.NotNil(t, indexBlob, "expected the registered index to have been added")
+
+	completedUpload, err := repo.GetUploadByID(ctx, upload.ID())
+	require.NoError(t, err)
+	require.Equal(t, uploadsmodel.UploadStateCompleted, completedUpload.State())
+
+	require.Len(t, svc.uploadAdds, 1, "expected the upload to be registered once")
+	require.Equal(t, rootCid.String(), svc.uploadAdds[0].Root.String())
+
+	shardBlobCids := make([]string, 0, len(shardBlobs))
+	for digest := range shardBlobs {
+		shardBlobCids = append(shardBlobCids, cid.NewCidV1(uint64(multicodec.Car), multihash.Multihash(digest)).String())
+	}
+	registeredShardCids := make([]string, 0, len(svc.uploadAdds[0].Shards))
+	for _, shard := range svc.uploadAdds[0].Shards {
+		registeredShardCids = append(registeredShardCids, shard.String())
+	}
+	require.ElementsMatch(t, shardBlobCids, registeredShardCids, "expected every added shard to be registered")
+
+	// Every block the index locates should be found at that position.
+	index, err := blobindex.Extract(bytes.NewReader(indexBlob))
+	require.NoError(t, err)
+	require.Equal(t, rootCid.String(), index.Content().String())
+	require.Equal(t, len(shardBlobs), index.Shards().Size())
+	for shardDigest, slices := range index.Shards().Iterator() {
+		shardBlob, ok := shardBlobs[string(shardDigest)]
+		require.True(t, ok, "expected indexed shard to have been added")
+		require.NotZero(t, slices.Size())
+		for sliceDigest, position := range slices.Iterator() {
+			require.LessOrEqual(t, position.Offset+position.Length, uint64(len(shardBlob)))
+			blockData := shardBlob[position.Offset : position.Offset+position.Length]
+			foundDigest, err := multihash.Sum(blockData, multihash.SHA2_256, -1)
+			require.NoError(t, err)
+			require.Equal(t, sliceDigest, foundDigest, "expected indexed block to be at its position")
+		}
+	}
+
+	blobBlockstores := make([]blockstore.Blockstore, 0, len(shardBlobs))
+	var rootShards int
+	for _, blob := range shardBlobs {
 		bs, err := blockstore.NewReadOnly(bytes.NewReader(blob), nil)
 		require.NoError(t, err)
 		blobBlockstores = append(blobBlockstores, bs)
+
+		roots, err := bs.Roots()
+		require.NoError(t, err)
+		if len(roots) > 0 {
+			require.Equal(t, []cid.Cid{rootCid}, roots, "expected a shard's only root to be the upload root")
+			rootShards++
+		}
 	}
+	require.Equal(t, 1, rootShards, "expected exactly one shard to declare the upload root")
 
 	bs := &compositeBlockstore{
 		blockstores: blobBlockstores,
@@ -222,3 +346,92 @@ func TestExecuteUpload(t *testing.T) {
 
 	require.True(t, areEqual, "expected all files to be present and match")
 }
+
+func TestExecuteUploadConcurrently(t *testing.T) {
+	// In case something goes wrong. This should never take this long.
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	t.Cleanup(cancel)
+
+	memFS := afero.NewMemMapFs()
+	paths := []string{"."}
+	for i := range 4 {
+		dir := fmt.Sprintf("dir%d", i)
+		memFS.MkdirAll(dir, 0755)
+		paths = append(paths, dir)
+		for j := range 4 {
+			path := fmt.Sprintf("%s/file%d", dir, j)
+			afero.WriteFile(memFS, path, randomBytes(1<<15+rand.Intn(1<<15)), 0644)
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		err := memFS.Chtimes(path, time.Now(), time.Now())
+		require.NoError(t, err)
+	}
+
+	// Uploads the tree, returning the root and the blocks in the shards added
+	// for it. Which shard each block lands in can vary from run to run, since
+	// DAG scans start while the file system is still being scanned, so compare
+	// the blocks rather than the shards.
+	upload := func(t *testing.T, concurrency int) (cid.Cid, []string) {
+		// Concurrent scans need a database which can handle concurrent writers,
+		// which the shared in-memory test database can't.
+		repo := sqlrepo.New(testutil.CreateTestFileDB(t))
+
+		svc := &testService{}
+		putClient := ctestutil.NewPutClient()
+		c := newTestClient(t, svc, putClient)
+
+		api := preparation.NewAPI(
+			repo,
+			c,
+			c.Issuer().DID(),
+			preparation.WithGetLocalFSForPathFn(func(path string) (fs.FS, error) {
+				return afero.NewIOFS(memFS), nil
+			}),
+			preparation.WithShardParallelism(concurrency),
+		)
+
+		configuration, err := api.CreateConfiguration(ctx, "Concurrent Configuration", configurationsmodel.WithShardSize(1<<16))
+		require.NoError(t, err)
+		source, err := api.CreateSource(ctx, "Concurrent Source", ".")
+		require.NoError(t, err)
+		err = repo.AddSourceToConfiguration(ctx, configuration.ID(), source.ID())
+		require.NoError(t, err)
+		uploads, err := api.CreateUploads(ctx, configuration.ID())
+		require.NoError(t, err)
+		require.Len(t, uploads, 1)
+
+		rootCid, err := api.ExecuteUpload(ctx, uploads[0], uploadsapi.WithDAGScanConcurrency(concurrency))
+		require.NoError(t, err)
+
+		require.Len(t, svc.uploadAdds, 1)
+		require.Len(t, svc.indexAdds, 1)
+		require.NotEmpty(t, svc.uploadAdds[0].Shards)
+
+		var blockCids []string
+		for _, blob := range ctestutil.ReceivedBlobs(putClient) {
+			digest, err := multihash.Sum(blob, multihash.SHA2_256, -1)
+			require.NoError(t, err)
+			if cid.NewCidV1(uint64(multicodec.Car), digest).String() == svc.indexAdds[0].Index.String() {
+				continue
+			}
+
+			bs, err := blockstore.NewReadOnly(bytes.NewReader(blob), nil)
+			require.NoError(t, err)
+			keys, err := bs.AllKeysChan(ctx)
+			require.NoError(t, err)
+			for key := range keys {
+				blockCids = append(blockCids, key.String())
+			}
+		}
+		return rootCid, blockCids
+	}
+
+	sequentialRoot, sequentialBlocks := upload(t, 1)
+	concurrentRoot, concurrentBlocks := upload(t, 4)
+
+	// Each block is added once, however many scans run at once.
+	require.Equal(t, sequentialRoot, concurrentRoot)
+	require.ElementsMatch(t, sequentialBlocks, concurrentBlocks)
+}
@@ -35,7 +35,15 @@ type Shard struct {
 	id       id.ShardID
 	uploadID id.UploadID
 	cid      cid.Cid
-	state    ShardState
+	// root is the root CID the shard's CAR header declares. Only the shard
+	// holding the upload's root node has one.
+	root cid.Cid
+	// size is the total encoded length of the blocks in the shard's CAR, not
+	// including the header.
+	size uint64
+	// blockCount is the number of blocks in the shard.
+	blockCount uint64
+	state      ShardState
 }
 
 // NewShard creates a new Shard with the given fsEntryID.
@@ -44,6 +52,7 @@ func NewShard(uploadID id.UploadID) (*Shard, error) {
 		id:       id.New(),
 		uploadID: uploadID,
 		cid:      cid.Undef,
+		root:     cid.Undef,
 		state:    ShardStateOpen,
 	}
 	if _, err := validateShard(s); err != nil {
@@ -60,6 +69,9 @@ func validateShard(s *Shard) (*Shard, error) {
 	if !validShardState(s.state) {
 		return nil, fmt.Errorf("invalid shard state: %s", s.state)
 	}
+	if s.state == ShardStateAdded && s.cid == cid.Undef {
+		return nil, types.ErrEmpty{Field: "cid"}
+	}
 	return s, nil
 }
 
@@ -71,10 +83,29 @@ func (s *Shard) Close() error {
 	return nil
 }
 
-func (s *Shard) Added() error {
+// CloseWithRoot closes the shard, recording the root CID its CAR header should
+// declare. This is used for the shard holding the upload's root node.
+func (s *Shard) CloseWithRoot(root cid.Cid) error {
+	if root == cid.Undef {
+		return types.ErrEmpty{Field: "root"}
+	}
+	if err := s.Close(); err != nil {
+		return err
+	}
+	s.root = root
+	return nil
+}
+
+// Added marks the shard as added to the space, recording the CID of the CAR
+// that was added.
+func (s *Shard) Added(shardCID cid.Cid) error {
 	if s.state != ShardStateClosed {
 		return fmt.Errorf("cannot add shard in state %s", s.state)
 	}
+	if shardCID == cid.Undef {
+		return types.ErrEmpty{Field: "cid"}
+	}
+	s.cid = shardCID
 	s.state = ShardStateAdded
 	return nil
 }
@@ -83,6 +114,9 @@ type ShardScanner func(
 	id *id.ShardID,
 	uploadID *id.UploadID,
 	cid *cid.Cid,
+	root *cid.Cid,
+	size *uint64,
+	blockCount *uint64,
 	state *ShardState,
 ) error
 
@@ -92,6 +126,9 @@ func ReadShardFromDatabase(scanner ShardScanner) (*Shard, error) {
 		&shard.id,
 		&shard.uploadID,
 		&shard.cid,
+		&shard.root,
+		&shard.size,
+		&shard.blockCount,
 		&shard.state,
 	)
 	if err != nil {
@@ -101,7 +138,7 @@ func ReadShardFromDatabase(scanner ShardScanner) (*Shard, error) {
 }
 
 // ShardWriter is a function type for writing a Shard to the database.
-type ShardWriter func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, state ShardState) error
+type ShardWriter func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, root cid.Cid, size uint64, blockCount uint64, state ShardState) error
 
 // WriteShardToDatabase writes a Shard to the database using the provided writer function.
 func WriteShardToDatabase(shard *Shard, writer ShardWriter) error {
@@ -109,6 +146,9 @@ func WriteShardToDatabase(shard *Shard, writer ShardWriter) error {
 		shard.id,
 		shard.uploadID,
 		shard.cid,
+		shard.root,
+		shard.size,
+		shard.blockCount,
 		shard.state,
 	)
 }
@@ -117,6 +157,29 @@ func (s *Shard) ID() id.ShardID {
 	return s.id
 }
 
+// CID returns the CID of the shard's CAR, which is only known once the shard
+// has been added to the space.
+func (s *Shard) CID() cid.Cid {
+	return s.cid
+}
+
+// Root returns the root CID the shard's CAR header declares, or [cid.Undef] if
+// it has none.
+func (s *Shard) Root() cid.Cid {
+	return s.root
+}
+
+// Size returns the total encoded length of the blocks in the shard's CAR, not
+// including the header.
+func (s *Shard) Size() uint64 {
+	return s.size
+}
+
+// BlockCount returns the number of blocks in the shard.
+func (s *Shard) BlockCount() uint64 {
+	return s.blockCount
+}
+
 func (s *Shard) State() ShardState {
 	return s.state
 }
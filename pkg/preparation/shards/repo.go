@@ -16,7 +16,19 @@ type Repo interface {
 	UpdateShard(ctx context.Context, shard *model.Shard) error
 	ShardsForUploadByStatus(ctx context.Context, uploadID id.UploadID, state model.ShardState) ([]*model.Shard, error)
 	GetConfigurationByUploadID(ctx context.Context, uploadID id.UploadID) (*configurationsmodel.Configuration, error)
-	AddNodeToShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid) error
+	// AddNodeToShard adds the node to the shard, growing the shard's size by
+	// `encodedSize`, the length of the node's block as encoded in a CAR.
+	AddNodeToShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid, encodedSize uint64) error
+	// ShardForNode returns the shard of the given upload which holds the node
+	// with the given CID, or nil if no shard of the upload holds it. If more
+	// than one does, it returns the one the node was most recently added to.
+	ShardForNode(ctx context.Context, uploadID id.UploadID, nodeCID cid.Cid) (*model.Shard, error)
 	FindNodeByCid(ctx context.Context, c cid.Cid) (dagsmodel.Node, error)
 	ForEachNode(ctx context.Context, shardID id.ShardID, yield func(dagsmodel.Node) error) error
+	// SetNodeOffsetInShard records the offset of the node's block data within
+	// the shard's CAR.
+	SetNodeOffsetInShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid, offset uint64) error
+	// ForEachNodePosition calls `yield` with the offset and length of the block
+	// data of each node in the shard's CAR. The offsets must have been recorded.
+	ForEachNodePosition(ctx context.Context, shardID id.ShardID, yield func(nodeCID cid.Cid, offset uint64, length uint64) error) error
 }
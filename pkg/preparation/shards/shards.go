@@ -1,23 +1,30 @@
 package shards
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multicodec"
 	"github.com/multiformats/go-multihash"
 	"github.com/multiformats/go-varint"
+	"github.com/storacha/go-libstoracha/blobindex"
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/did"
 	"github.com/storacha/guppy/pkg/client"
 	configmodel "github.com/storacha/guppy/pkg/preparation/configurations/model"
-	dagsmodel "github.com/storacha/guppy/pkg/preparation/dags/model"
 	"github.com/storacha/guppy/pkg/preparation/shards/model"
 	"github.com/storacha/guppy/pkg/preparation/types/id"
 	"github.com/storacha/guppy/pkg/preparation/uploads"
+	"golang.org/x/sync/errgroup"
 )
 
 // Byte length of a CBOR encoded CAR header with zero roots.
@@ -33,17 +40,43 @@ type SpaceBlobAdder interface {
 
 var _ SpaceBlobAdder = (*client.Client)(nil)
 
+// UploadAdder is an interface for registering an upload's shards with the
+// service. It's typically implemented by [client.Client].
+type UploadAdder interface {
+	UploadAdd(ctx context.Context, space did.DID, root ipld.Link, shards []ipld.Link) (uploadcap.AddOk, error)
+}
+
+var _ UploadAdder = (*client.Client)(nil)
+
+// IndexAdder is an interface for registering an index with the service. It's
+// typically implemented by [client.Client].
+type IndexAdder interface {
+	SpaceIndexAdd(ctx context.Context, space did.DID, index ipld.Link) error
+}
+
+var _ IndexAdder = (*client.Client)(nil)
+
 // API provides methods to interact with the Shards in the repository.
 type API struct {
 	Repo        Repo
 	Client      SpaceBlobAdder
+	UploadAdder UploadAdder
+	IndexAdder  IndexAdder
 	Space       did.DID
-	CarForShard func(ctx context.Context, shard *model.Shard) (io.Reader, error)
+	// CarForShard returns a stream of the CAR for the shard. It's called once to
+	// compute the CAR's digest, again to upload it, and again for each retry of
+	// the upload, so it must produce the same bytes each time.
+	CarForShard func(ctx context.Context, shard *model.Shard) (io.ReadCloser, error)
+	// Parallelism is how many shards may be in flight to the service at once.
+	// Values below 1 mean one at a time.
+	Parallelism int
 }
 
 var _ uploads.AddNodeToUploadShardsFunc = API{}.AddNodeToUploadShards
 var _ uploads.CloseUploadShardsFunc = API{}.CloseUploadShards
 var _ uploads.SpaceBlobAddShardsForUploadFunc = API{}.SpaceBlobAddShardsForUpload
+var _ uploads.AddIndexForUploadFunc = API{}.AddIndexForUpload
+var _ uploads.RegisterUploadShardsFunc = API{}.RegisterUploadShards
 
 func (a API) AddNodeToUploadShards(ctx context.Context, uploadID id.UploadID, nodeCID cid.Cid) (bool, error) {
 	config, err := a.Repo.GetConfigurationByUploadID(ctx, uploadID)
@@ -55,6 +88,15 @@ func (a API) AddNodeToUploadShards(ctx context.Context, uploadID id.UploadID, no
 		return false, fmt.Errorf("failed to get open shards for upload %s: %w", uploadID, err)
 	}
 
+	node, err := a.Repo.FindNodeByCid(ctx, nodeCID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find node %s: %w", nodeCID, err)
+	}
+	if node == nil {
+		return false, fmt.Errorf("node %s not found", nodeCID)
+	}
+	nodeSize := nodeEncodingLength(nodeCID, node.Size())
+
 	var shard *model.Shard
 	var closed bool
 
@@ -62,11 +104,7 @@ func (a API) AddNodeToUploadShards(ctx context.Context, uploadID id.UploadID, no
 	// have room. (There should only be at most one open shard, but there's no
 	// harm handling multiple if they exist.)
 	for _, s := range openShards {
-		hasRoom, err := a.roomInShard(ctx, s, nodeCID, config)
-		if err != nil {
-			return false, fmt.Errorf("failed to check room in shard %s for node %s: %w", s.ID(), nodeCID, err)
-		}
-		if hasRoom {
+		if roomInShard(s, nodeCID, nodeSize, config) {
 			shard = s
 			break
 		}
@@ -85,47 +123,25 @@ func (a API) AddNodeToUploadShards(ctx context.Context, uploadID id.UploadID, no
 		}
 	}
 
-	err = a.Repo.AddNodeToShard(ctx, shard.ID(), nodeCID)
+	err = a.Repo.AddNodeToShard(ctx, shard.ID(), nodeCID, nodeSize)
 	if err != nil {
 		return false, fmt.Errorf("failed to add node %s to shard %s for upload %s: %w", nodeCID, shard.ID(), uploadID, err)
 	}
 	return closed, nil
 }
 
-func (a *API) roomInShard(ctx context.Context, shard *model.Shard, nodeCID cid.Cid, config *configmodel.Configuration) (bool, error) {
-	node, err := a.Repo.FindNodeByCid(ctx, nodeCID)
-	if err != nil {
-		return false, fmt.Errorf("failed to find node %s: %w", nodeCID, err)
-	}
-	if node == nil {
-		return false, fmt.Errorf("node %s not found", nodeCID)
-	}
-	nodeSize := nodeEncodingLength(nodeCID, node.Size())
-
-	currentSize, err := a.currentSizeOfShard(ctx, shard.ID())
-	if err != nil {
-		return false, fmt.Errorf("failed to get current size of shard %s: %w", shard.ID(), err)
-	}
-
-	if currentSize+nodeSize > config.ShardSize() {
-		return false, nil // No room in the shard
-	}
-
-	return true, nil
-}
-
-func (a *API) currentSizeOfShard(ctx context.Context, shardID id.ShardID) (uint64, error) {
-	var totalSize uint64 = noRootsHeaderLen
+// roomInShard reports whether a node of the given encoded size fits in the
+// shard. It relies on the shard's running size, so it doesn't need to visit
+// the nodes already in the shard.
+func roomInShard(shard *model.Shard, nodeCID cid.Cid, nodeSize uint64, config *configmodel.Configuration) bool {
+	currentSize := noRootsHeaderLen + shard.Size()
 
-	err := a.Repo.ForEachNode(ctx, shardID, func(node dagsmodel.Node) error {
-		totalSize += nodeEncodingLength(node.CID(), node.Size())
-		return nil
-	})
-	if err != nil {
-		return 0, fmt.Errorf("failed to iterate over nodes in shard %s: %w", shardID, err)
-	}
+	// Any node might turn out to be the upload's root, which is always the last
+	// node added. If it is, the shard's header will declare it as the root, so
+	// leave room for that.
+	rootHeaderSize := rootHeaderEncodingLength(nodeCID) - noRootsHeaderLen
 
-	return totalSize, nil
+	return currentSize+nodeSize+rootHeaderSize <= config.ShardSize()
 }
 
 func nodeEncodingLength(cid cid.Cid, blockSize uint64) uint64 {
@@ -134,16 +150,76 @@ func nodeEncodingLength(cid cid.Cid, blockSize uint64) uint64 {
 	return pllen + vilen
 }
 
-func (a API) CloseUploadShards(ctx context.Context, uploadID id.UploadID) (bool, error) {
+// rootHeaderEncodingLength returns the byte length of a CBOR encoded CAR header
+// declaring the given single root, including its length prefix.
+func rootHeaderEncodingLength(root cid.Cid) uint64 {
+	// A CID is encoded as tag 42 (2 bytes) on a byte string of the CID bytes,
+	// prefixed with a 0x00 multibase identity byte.
+	cidlen := uint64(len(root.Bytes())) + 1
+	cidCborLen := 2 + cborHeaderLength(cidlen) + cidlen
+	// The map header (1), "roots" (6), the array header (1), the root,
+	// "version" (8) and 1 (1).
+	hdrlen := 1 + 6 + 1 + cidCborLen + 8 + 1
+	return hdrlen + uint64(varint.UvarintSize(hdrlen))
+}
+
+// cborHeaderLength returns the byte length of the CBOR major type header for
+// an item of the given length.
+func cborHeaderLength(n uint64) uint64 {
+	switch {
+	case n < 24:
+		return 1
+	case n <= 0xff:
+		return 2
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+// CloseUploadShards closes any remaining open shards for the upload. The shard
+// holding the upload's root node will declare `rootCID` as the root in its CAR
+// header.
+//
+// If no open shard of the upload holds the root node, because it was already in
+// a shard of an earlier upload, or in a shard of this one which has since
+// closed, it's added again, so that one of the upload's last shards holds and
+// declares it.
+func (a API) CloseUploadShards(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) (bool, error) {
+	var closed bool
+
+	rootShard, err := a.Repo.ShardForNode(ctx, uploadID, rootCID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find shard for root %s of upload %s: %w", rootCID, uploadID, err)
+	}
+	if rootShard == nil || rootShard.State() != model.ShardStateOpen {
+		closed, err = a.AddNodeToUploadShards(ctx, uploadID, rootCID)
+		if err != nil {
+			return false, fmt.Errorf("failed to add root %s to shards for upload %s: %w", rootCID, uploadID, err)
+		}
+		rootShard, err = a.Repo.ShardForNode(ctx, uploadID, rootCID)
+		if err != nil {
+			return false, fmt.Errorf("failed to find shard for root %s of upload %s: %w", rootCID, uploadID, err)
+		}
+	}
+
 	openShards, err := a.Repo.ShardsForUploadByStatus(ctx, uploadID, model.ShardStateOpen)
 	if err != nil {
 		return false, fmt.Errorf("failed to get open shards for upload %s: %w", uploadID, err)
 	}
 
-	var closed bool
-
 	for _, s := range openShards {
-		s.Close()
+		if s.ID() == rootShard.ID() {
+			err = s.CloseWithRoot(rootCID)
+		} else {
+			err = s.Close()
+		}
+		if err != nil {
+			return false, fmt.Errorf("closing shard %s for upload %s: %w", s.ID(), uploadID, err)
+		}
 		if err := a.Repo.UpdateShard(ctx, s); err != nil {
 			return false, fmt.Errorf("updating shard %s for upload %s: %w", s.ID(), uploadID, err)
 		}
@@ -153,27 +229,196 @@ func (a API) CloseUploadShards(ctx context.Context, uploadID id.UploadID) (bool,
 	return closed, nil
 }
 
+// SpaceBlobAddShardsForUpload adds each of the upload's closed shards to the
+// space, with up to [API.Parallelism] of them in flight at once.
 func (a API) SpaceBlobAddShardsForUpload(ctx context.Context, uploadID id.UploadID) error {
 	closedShards, err := a.Repo.ShardsForUploadByStatus(ctx, uploadID, model.ShardStateClosed)
 	if err != nil {
 		return fmt.Errorf("failed to get closed shards for upload %s: %w", uploadID, err)
 	}
 
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(a.Parallelism, 1))
 	for _, shard := range closedShards {
-		reader, err := a.CarForShard(ctx, shard)
+		// Each shard is only touched by its own goroutine, and the repo is safe
+		// for concurrent use.
+		eg.Go(func() error {
+			return a.spaceBlobAddShard(egCtx, shard)
+		})
+	}
+
+	return eg.Wait()
+}
+
+// spaceBlobAddShard adds a single closed shard to the space, and records it as
+// added.
+func (a API) spaceBlobAddShard(ctx context.Context, shard *model.Shard) error {
+	digest, size, err := a.digestShard(ctx, shard)
+	if err != nil {
+		return fmt.Errorf("failed to compute digest of shard %s: %w", shard.ID(), err)
+	}
+
+	reader, err := a.CarForShard(ctx, shard)
+	if err != nil {
+		return fmt.Errorf("failed to get CAR reader for shard %s: %w", shard.ID(), err)
+	}
+
+	// If the PUT fails, it's retried with the CAR streamed again from the start.
+	_, _, err = a.Client.SpaceBlobAdd(ctx, reader, a.Space,
+		client.WithPrecomputedDigest(digest, size),
+		client.WithReopen(func() (io.ReadCloser, error) { return a.CarForShard(ctx, shard) }),
+	)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to add shard %s to space %s: %w", shard.ID(), a.Space, err)
+	}
+	if err := shard.Added(cid.NewCidV1(uint64(multicodec.Car), digest)); err != nil {
+		return fmt.Errorf("marking shard %s as added: %w", shard.ID(), err)
+	}
+	if err := a.Repo.UpdateShard(ctx, shard); err != nil {
+		return fmt.Errorf("failed to update shard %s after adding to space: %w", shard.ID(), err)
+	}
+
+	return nil
+}
+
+// digestShard computes the SHA2-256 multihash and size of the shard's CAR in a
+// streaming pass, so the CAR can later be streamed to the service. Along the
+// way, it records where each block's data lies in the CAR, for the upload's
+// index, so that the later streams needn't.
+func (a API) digestShard(ctx context.Context, shard *model.Shard) (multihash.Multihash, uint64, error) {
+	reader, err := a.CarForShard(ctx, shard)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get CAR reader for shard %s: %w", shard.ID(), err)
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	size, err := forEachBlockOffset(io.TeeReader(reader, hasher), func(nodeCID cid.Cid, offset uint64) error {
+		return a.Repo.SetNodeOffsetInShard(ctx, shard.ID(), nodeCID, offset)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading CAR for shard %s: %w", shard.ID(), err)
+	}
+
+	digest, err := multihash.Encode(hasher.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return nil, 0, fmt.Errorf("encoding digest for shard %s: %w", shard.ID(), err)
+	}
+
+	return digest, size, nil
+}
+
+// forEachBlockOffset reads the whole CAR from `r`, calling `yield` with the CID
+// of each block and the offset of the block's data within the CAR. It returns
+// the length of the CAR.
+func forEachBlockOffset(r io.Reader, yield func(nodeCID cid.Cid, offset uint64) error) (uint64, error) {
+	br := bufio.NewReader(r)
+	var offset uint64
+	for section := 0; ; section++ {
+		sectionLen, err := varint.ReadUvarint(br)
+		if err == io.EOF {
+			return offset, nil
+		}
 		if err != nil {
-			return fmt.Errorf("failed to get CAR reader for shard %s: %w", shard.ID(), err)
+			return 0, fmt.Errorf("reading length of section %d: %w", section, err)
 		}
+		start := offset + uint64(varint.UvarintSize(sectionLen))
+		offset = start + sectionLen
 
-		_, _, err = a.Client.SpaceBlobAdd(ctx, reader, a.Space)
+		// The first section is the header.
+		var cidLen int
+		if section > 0 {
+			var nodeCID cid.Cid
+			cidLen, nodeCID, err = cid.CidFromReader(br)
+			if err != nil {
+				return 0, fmt.Errorf("reading CID of section %d: %w", section, err)
+			}
+			if err := yield(nodeCID, start+uint64(cidLen)); err != nil {
+				return 0, err
+			}
+		}
+
+		if _, err := io.CopyN(io.Discard, br, int64(sectionLen)-int64(cidLen)); err != nil {
+			return 0, fmt.Errorf("reading section %d: %w", section, err)
+		}
+	}
+}
+
+// AddIndexForUpload builds a sharded DAG index of the upload, locating every
+// block within the shards, then stores it in the space with `space/blob/add`
+// and registers it with `space/index/add`. Every shard must already have been
+// added to the space.
+func (a API) AddIndexForUpload(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) error {
+	addedShards, err := a.Repo.ShardsForUploadByStatus(ctx, uploadID, model.ShardStateAdded)
+	if err != nil {
+		return fmt.Errorf("failed to get added shards for upload %s: %w", uploadID, err)
+	}
+
+	index := blobindex.NewShardedDagIndexView(cidlink.Link{Cid: rootCID}, len(addedShards))
+	for _, shard := range addedShards {
+		err := a.Repo.ForEachNodePosition(ctx, shard.ID(), func(nodeCID cid.Cid, offset uint64, length uint64) error {
+			index.SetSlice(shard.CID().Hash(), nodeCID.Hash(), blobindex.Position{
+				Offset: offset,
+				Length: length,
+			})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index shard %s: %w", shard.ID(), err)
+		}
+	}
+
+	archive, err := index.Archive()
+	if err != nil {
+		return fmt.Errorf("failed to archive index for upload %s: %w", uploadID, err)
+	}
+	archiveBytes, err := io.ReadAll(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read index archive for upload %s: %w", uploadID, err)
+	}
+
+	digest, _, err := a.Client.SpaceBlobAdd(ctx, bytes.NewReader(archiveBytes), a.Space)
+	if err != nil {
+		return fmt.Errorf("failed to add index for upload %s to space %s: %w", uploadID, a.Space, err)
+	}
+
+	indexLink := cidlink.Link{Cid: cid.NewCidV1(uint64(multicodec.Car), digest)}
+	if err := a.IndexAdder.SpaceIndexAdd(ctx, a.Space, indexLink); err != nil {
+		return fmt.Errorf("failed to register index for upload %s in space %s: %w", uploadID, a.Space, err)
+	}
+
+	return nil
+}
+
+// RegisterUploadShards registers the upload with the service via `upload/add`,
+// linking the root CID to the CARs of all of the upload's shards. Every shard
+// must already have been added to the space.
+func (a API) RegisterUploadShards(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) error {
+	for _, state := range []model.ShardState{model.ShardStateOpen, model.ShardStateClosed} {
+		pending, err := a.Repo.ShardsForUploadByStatus(ctx, uploadID, state)
 		if err != nil {
-			return fmt.Errorf("failed to add shard %s to space %s: %w", shard.ID(), a.Space, err)
+			return fmt.Errorf("failed to get %s shards for upload %s: %w", state, uploadID, err)
 		}
-		shard.Added()
-		if err := a.Repo.UpdateShard(ctx, shard); err != nil {
-			return fmt.Errorf("failed to update shard %s after adding to space: %w", shard.ID(), err)
+		if len(pending) > 0 {
+			return fmt.Errorf("upload %s still has %d %s shards", uploadID, len(pending), state)
 		}
 	}
 
+	addedShards, err := a.Repo.ShardsForUploadByStatus(ctx, uploadID, model.ShardStateAdded)
+	if err != nil {
+		return fmt.Errorf("failed to get added shards for upload %s: %w", uploadID, err)
+	}
+
+	shardLinks := make([]ipld.Link, 0, len(addedShards))
+	for _, shard := range addedShards {
+		shardLinks = append(shardLinks, cidlink.Link{Cid: shard.CID()})
+	}
+
+	_, err = a.UploadAdder.UploadAdd(ctx, a.Space, cidlink.Link{Cid: rootCID}, shardLinks)
+	if err != nil {
+		return fmt.Errorf("failed to register upload %s in space %s: %w", uploadID, a.Space, err)
+	}
+
 	return nil
 }
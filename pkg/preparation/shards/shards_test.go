@@ -1,19 +1,30 @@
 package shards_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-cid"
+	ipldcar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/multiformats/go-multicodec"
 	"github.com/multiformats/go-multihash"
+	"github.com/multiformats/go-varint"
+	"github.com/storacha/go-libstoracha/blobindex"
+	uploadcap "github.com/storacha/go-libstoracha/capabilities/upload"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/did"
 	"github.com/storacha/guppy/pkg/client"
 	configurationsmodel "github.com/storacha/guppy/pkg/preparation/configurations/model"
+	dagsmodel "github.com/storacha/guppy/pkg/preparation/dags/model"
 	"github.com/storacha/guppy/pkg/preparation/shards"
 	"github.com/storacha/guppy/pkg/preparation/shards/model"
 	"github.com/storacha/guppy/pkg/preparation/sqlrepo"
@@ -76,6 +87,13 @@ func TestAddNodeToUploadShardsAndCloseUploadShards(t *testing.T) {
 	foundNodeCids = nodesInShard(t.Context(), t, db, firstShard.ID())
 	require.ElementsMatch(t, []cid.Cid{nodeCid1, nodeCid2}, foundNodeCids)
 
+	// the shard keeps a running total of its size and block count
+	openShards, err = repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateOpen)
+	require.NoError(t, err)
+	require.Len(t, openShards, 1)
+	require.Equal(t, uint64(2), openShards[0].BlockCount())
+	require.Equal(t, 2*encodedNodeLength(nodeCid1, 1<<14), openShards[0].Size())
+
 	// with an open shard without room, closes the shard and creates another
 
 	nodeCid3 := testutil.RandomCID(t)
@@ -103,9 +121,10 @@ func TestAddNodeToUploadShardsAndCloseUploadShards(t *testing.T) {
 	foundNodeCids = nodesInShard(t.Context(), t, db, secondShard.ID())
 	require.ElementsMatch(t, []cid.Cid{nodeCid3}, foundNodeCids)
 
-	// finally, close the last shard with CloseUploadShards()
+	// finally, close the last shard with CloseUploadShards(), treating the last
+	// node as the root
 
-	shardClosed, err = api.CloseUploadShards(t.Context(), upload.ID())
+	shardClosed, err = api.CloseUploadShards(t.Context(), upload.ID(), nodeCid3)
 	require.NoError(t, err)
 	require.True(t, shardClosed)
 
@@ -120,11 +139,128 @@ func TestAddNodeToUploadShardsAndCloseUploadShards(t *testing.T) {
 	}
 	require.ElementsMatch(t, closedShardIDs, []id.ShardID{firstShard.ID(), secondShard.ID()})
 
+	// only the shard holding the root declares it
+	for _, closedShard := range closedShards {
+		if closedShard.ID() == secondShard.ID() {
+			require.Equal(t, nodeCid3, closedShard.Root())
+		} else {
+			require.Equal(t, cid.Undef, closedShard.Root())
+		}
+	}
+
+	rootShard, err := repo.ShardForNode(t.Context(), upload.ID(), nodeCid3)
+	require.NoError(t, err)
+	require.NotNil(t, rootShard)
+	require.Equal(t, secondShard.ID(), rootShard.ID())
+
 	openShards, err = repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateOpen)
 	require.NoError(t, err)
 	require.Len(t, openShards, 0)
 }
 
+func TestCloseUploadShardsWithDeduplicatedRoot(t *testing.T) {
+	// Checks that exactly one of the upload's shards declares the root, and that
+	// that shard holds it.
+	requireOneRootShard := func(t *testing.T, db *sql.DB, repo shards.Repo, uploadID id.UploadID, rootCid cid.Cid) {
+		closedShards, err := repo.ShardsForUploadByStatus(t.Context(), uploadID, model.ShardStateClosed)
+		require.NoError(t, err)
+
+		var rootShards []*model.Shard
+		for _, closedShard := range closedShards {
+			if closedShard.Root() == rootCid {
+				rootShards = append(rootShards, closedShard)
+			} else {
+				require.Equal(t, cid.Undef, closedShard.Root())
+			}
+		}
+		require.Len(t, rootShards, 1, "expected exactly one shard of the upload to declare the root")
+		require.Contains(t, nodesInShard(t.Context(), t, db, rootShards[0].ID()), rootCid)
+	}
+
+	t.Run("declares a root already in a shard of an earlier upload", func(t *testing.T) {
+		db := testutil.CreateTestDB(t)
+		repo := sqlrepo.New(db)
+		api := shards.API{Repo: repo}
+
+		configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
+		require.NoError(t, err)
+		source1, err := repo.CreateSource(t.Context(), "Test Source 1", ".")
+		require.NoError(t, err)
+		source2, err := repo.CreateSource(t.Context(), "Test Source 2", ".")
+		require.NoError(t, err)
+		uploads, err := repo.CreateUploads(t.Context(), configuration.ID(), []id.SourceID{source1.ID(), source2.ID()})
+		require.NoError(t, err)
+		require.Len(t, uploads, 2)
+
+		rootCid := testutil.RandomCID(t)
+		_, _, err = repo.FindOrCreateRawNode(t.Context(), rootCid, 1<<10, "some/path", source1.ID(), 0)
+		require.NoError(t, err)
+		_, err = api.AddNodeToUploadShards(t.Context(), uploads[0].ID(), rootCid)
+		require.NoError(t, err)
+		_, err = api.CloseUploadShards(t.Context(), uploads[0].ID(), rootCid)
+		require.NoError(t, err)
+
+		// The second upload shares the root, so it was never added to its shards.
+		otherCid := testutil.RandomCID(t)
+		_, _, err = repo.FindOrCreateRawNode(t.Context(), otherCid, 1<<10, "some/other/path", source2.ID(), 0)
+		require.NoError(t, err)
+		_, err = api.AddNodeToUploadShards(t.Context(), uploads[1].ID(), otherCid)
+		require.NoError(t, err)
+
+		shardClosed, err := api.CloseUploadShards(t.Context(), uploads[1].ID(), rootCid)
+		require.NoError(t, err)
+		require.True(t, shardClosed)
+
+		requireOneRootShard(t, db, repo, uploads[0].ID(), rootCid)
+		requireOneRootShard(t, db, repo, uploads[1].ID(), rootCid)
+	})
+
+	t.Run("declares a root already in a closed shard of the same upload", func(t *testing.T) {
+		db := testutil.CreateTestDB(t)
+		repo := sqlrepo.New(db)
+		api := shards.API{Repo: repo}
+
+		configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
+		require.NoError(t, err)
+		source, err := repo.CreateSource(t.Context(), "Test Source", ".")
+		require.NoError(t, err)
+		uploads, err := repo.CreateUploads(t.Context(), configuration.ID(), []id.SourceID{source.ID()})
+		require.NoError(t, err)
+		upload := uploads[0]
+
+		rootCid := testutil.RandomCID(t)
+		_, _, err = repo.FindOrCreateRawNode(t.Context(), rootCid, 1<<15, "some/path", source.ID(), 0)
+		require.NoError(t, err)
+		_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), rootCid)
+		require.NoError(t, err)
+
+		// Doesn't fit alongside the root, so the root's shard is closed.
+		otherCid := testutil.RandomCID(t)
+		_, _, err = repo.FindOrCreateRawNode(t.Context(), otherCid, 1<<15, "some/other/path", source.ID(), 0)
+		require.NoError(t, err)
+		shardClosed, err := api.AddNodeToUploadShards(t.Context(), upload.ID(), otherCid)
+		require.NoError(t, err)
+		require.True(t, shardClosed)
+
+		shardClosed, err = api.CloseUploadShards(t.Context(), upload.ID(), rootCid)
+		require.NoError(t, err)
+		require.True(t, shardClosed)
+
+		requireOneRootShard(t, db, repo, upload.ID(), rootCid)
+
+		openShards, err := repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateOpen)
+		require.NoError(t, err)
+		require.Len(t, openShards, 0)
+	})
+}
+
+// encodedNodeLength returns the length of a raw block of the given size as
+// encoded in a CAR: a varint length prefix, the CID and the data.
+func encodedNodeLength(c cid.Cid, size uint64) uint64 {
+	sectionLen := uint64(len(c.Bytes())) + size
+	return uint64(varint.UvarintSize(sectionLen)) + sectionLen
+}
+
 // (Until the repo has a way to query for this itself...)
 func nodesInShard(ctx context.Context, t *testing.T, db *sql.DB, shardID id.ShardID) []cid.Cid {
 	rows, err := db.QueryContext(ctx, `SELECT node_cid FROM nodes_in_shards WHERE shard_id = ?`, shardID)
@@ -141,8 +277,41 @@ func nodesInShard(ctx context.Context, t *testing.T, db *sql.DB, shardID id.Shar
 	return foundNodeCids
 }
 
+// carForShard returns a `CarForShard` which writes a CAR of the nodes in each
+// shard, with zeroes for each node's data.
+func carForShard(t *testing.T, repo shards.Repo) func(ctx context.Context, shard *model.Shard) (io.ReadCloser, error) {
+	return func(ctx context.Context, shard *model.Shard) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		err := ipldcar.WriteHeader(&ipldcar.CarHeader{Roots: []cid.Cid{}, Version: 1}, &buf)
+		require.NoError(t, err)
+		err = repo.ForEachNode(ctx, shard.ID(), func(node dagsmodel.Node) error {
+			return carutil.LdWrite(&buf, node.CID().Bytes(), make([]byte, node.Size()))
+		})
+		require.NoError(t, err)
+		return io.NopCloser(&buf), nil
+	}
+}
+
+// carBlockCids returns the CIDs of the blocks in a CAR, in order.
+func carBlockCids(t *testing.T, car []byte) []cid.Cid {
+	br := bufio.NewReader(bytes.NewReader(car))
+	_, err := carutil.LdRead(br)
+	require.NoError(t, err)
+
+	var cids []cid.Cid
+	for {
+		c, _, err := carutil.ReadNode(br)
+		if err == io.EOF {
+			return cids
+		}
+		require.NoError(t, err)
+		cids = append(cids, c)
+	}
+}
+
 type mockSpaceBlobAdder struct {
 	T           *testing.T
+	mu          sync.Mutex
 	invocations []spaceBlobAddInvocation
 }
 
@@ -157,12 +326,83 @@ func (m *mockSpaceBlobAdder) SpaceBlobAdd(ctx context.Context, content io.Reader
 	contentBytes, err := io.ReadAll(content)
 	require.NoError(m.T, err, "reading content for SpaceBlobAdd")
 
+	m.mu.Lock()
 	m.invocations = append(m.invocations, spaceBlobAddInvocation{
 		contentRead:  contentBytes,
 		spaceAddedTo: space,
 	})
+	m.mu.Unlock()
+
+	digest, err := multihash.Sum(contentBytes, multihash.SHA2_256, -1)
+	require.NoError(m.T, err, "hashing content for SpaceBlobAdd")
+
+	return digest, nil, nil
+}
+
+// slowSpaceBlobAdder is a [mockSpaceBlobAdder] which takes a moment over each
+// add, recording the most adds it saw in flight at once.
+type slowSpaceBlobAdder struct {
+	mockSpaceBlobAdder
+	inFlightMu  sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (m *slowSpaceBlobAdder) SpaceBlobAdd(ctx context.Context, content io.Reader, space did.DID, options ...client.SpaceBlobAddOption) (multihash.Multihash, delegation.Delegation, error) {
+	m.inFlightMu.Lock()
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.inFlightMu.Unlock()
+
+	defer func() {
+		m.inFlightMu.Lock()
+		m.inFlight--
+		m.inFlightMu.Unlock()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	return m.mockSpaceBlobAdder.SpaceBlobAdd(ctx, content, space, options...)
+}
+
+type mockUploadAdder struct {
+	invocations []uploadAddInvocation
+}
+
+type uploadAddInvocation struct {
+	space  did.DID
+	root   ipld.Link
+	shards []ipld.Link
+}
+
+var _ shards.UploadAdder = (*mockUploadAdder)(nil)
+
+func (m *mockUploadAdder) UploadAdd(ctx context.Context, space did.DID, root ipld.Link, shards []ipld.Link) (uploadcap.AddOk, error) {
+	m.invocations = append(m.invocations, uploadAddInvocation{
+		space:  space,
+		root:   root,
+		shards: shards,
+	})
+
+	return uploadcap.AddOk{Root: root, Shards: shards}, nil
+}
 
-	return []byte{}, nil, nil
+type mockIndexAdder struct {
+	invocations []indexAddInvocation
+}
+
+type indexAddInvocation struct {
+	space did.DID
+	index ipld.Link
+}
+
+var _ shards.IndexAdder = (*mockIndexAdder)(nil)
+
+func (m *mockIndexAdder) SpaceIndexAdd(ctx context.Context, space did.DID, index ipld.Link) error {
+	m.invocations = append(m.invocations, indexAddInvocation{
+		space: space,
+		index: index,
+	})
+	return nil
 }
 
 func TestSpaceBlobAddShardsForUpload(t *testing.T) {
@@ -173,21 +413,11 @@ func TestSpaceBlobAddShardsForUpload(t *testing.T) {
 		require.NoError(t, err)
 		spaceBlobAdder := mockSpaceBlobAdder{T: t}
 
-		carForShard := func(ctx context.Context, shard *model.Shard) (io.Reader, error) {
-			nodes := nodesInShard(ctx, t, db, shard.ID())
-			b := []byte("CAR CONTAINING NODES:")
-			for _, n := range nodes {
-				b = append(b, ' ')
-				b = append(b, []byte(n.String())...)
-			}
-			return bytes.NewReader(b), nil
-		}
-
 		api := shards.API{
 			Repo:        repo,
 			Client:      &spaceBlobAdder,
 			Space:       spaceDID,
-			CarForShard: carForShard,
+			CarForShard: carForShard(t, repo),
 		}
 
 		configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
@@ -224,11 +454,11 @@ func TestSpaceBlobAddShardsForUpload(t *testing.T) {
 		// This run should `space/blob/add` the first, closed shard.
 		require.Len(t, spaceBlobAdder.invocations, 1)
 		require.NotEmpty(t, spaceBlobAdder.invocations[0].contentRead)
-		require.Equal(t, fmt.Appendf(nil, "CAR CONTAINING NODES: %s %s", nodeCid1, nodeCid2), spaceBlobAdder.invocations[0].contentRead)
+		require.Equal(t, []cid.Cid{nodeCid1, nodeCid2}, carBlockCids(t, spaceBlobAdder.invocations[0].contentRead))
 		require.Equal(t, spaceDID, spaceBlobAdder.invocations[0].spaceAddedTo)
 
 		// Now close the upload shards and run it again.
-		_, err = api.CloseUploadShards(t.Context(), upload.ID())
+		_, err = api.CloseUploadShards(t.Context(), upload.ID(), nodeCid3)
 		require.NoError(t, err)
 		err = api.SpaceBlobAddShardsForUpload(t.Context(), upload.ID())
 		require.NoError(t, err)
@@ -236,7 +466,283 @@ func TestSpaceBlobAddShardsForUpload(t *testing.T) {
 		// This run should `space/blob/add` the second, newly closed shard.
 		require.Len(t, spaceBlobAdder.invocations, 2)
 		require.NotEmpty(t, spaceBlobAdder.invocations[1].contentRead)
-		require.Equal(t, fmt.Appendf(nil, "CAR CONTAINING NODES: %s", nodeCid3), spaceBlobAdder.invocations[1].contentRead)
+		require.Equal(t, []cid.Cid{nodeCid3}, carBlockCids(t, spaceBlobAdder.invocations[1].contentRead))
 		require.Equal(t, spaceDID, spaceBlobAdder.invocations[1].spaceAddedTo)
 	})
+
+	t.Run("adds up to `Parallelism` shards at once", func(t *testing.T) {
+		db := testutil.CreateTestFileDB(t)
+		repo := sqlrepo.New(db)
+		spaceDID, err := did.Parse("did:storacha:space:example")
+		require.NoError(t, err)
+		spaceBlobAdder := slowSpaceBlobAdder{mockSpaceBlobAdder: mockSpaceBlobAdder{T: t}}
+
+		api := shards.API{
+			Repo:        repo,
+			Client:      &spaceBlobAdder,
+			Space:       spaceDID,
+			CarForShard: carForShard(t, repo),
+			Parallelism: 2,
+		}
+
+		configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
+		require.NoError(t, err)
+		source, err := repo.CreateSource(t.Context(), "Test Source", ".")
+		require.NoError(t, err)
+		uploads, err := repo.CreateUploads(t.Context(), configuration.ID(), []id.SourceID{source.ID()})
+		require.NoError(t, err)
+		require.Len(t, uploads, 1)
+		upload := uploads[0]
+
+		// Each node fills a shard of its own.
+		var lastNodeCid cid.Cid
+		for range 4 {
+			lastNodeCid = testutil.RandomCID(t)
+			_, _, err = repo.FindOrCreateRawNode(t.Context(), lastNodeCid, 1<<15, "some/path", source.ID(), 0)
+			require.NoError(t, err)
+			_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), lastNodeCid)
+			require.NoError(t, err)
+		}
+		_, err = api.CloseUploadShards(t.Context(), upload.ID(), lastNodeCid)
+		require.NoError(t, err)
+
+		err = api.SpaceBlobAddShardsForUpload(t.Context(), upload.ID())
+		require.NoError(t, err)
+
+		require.Len(t, spaceBlobAdder.invocations, 4)
+		require.Equal(t, 2, spaceBlobAdder.maxInFlight)
+
+		addedShards, err := repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateAdded)
+		require.NoError(t, err)
+		require.Len(t, addedShards, 4)
+	})
+}
+
+func TestRegisterUploadShards(t *testing.T) {
+	db := testutil.CreateTestDB(t)
+	repo := sqlrepo.New(db)
+	spaceDID, err := did.Parse("did:storacha:space:example")
+	require.NoError(t, err)
+	spaceBlobAdder := mockSpaceBlobAdder{T: t}
+	uploadAdder := mockUploadAdder{}
+
+	api := shards.API{
+		Repo:        repo,
+		Client:      &spaceBlobAdder,
+		UploadAdder: &uploadAdder,
+		Space:       spaceDID,
+		CarForShard: carForShard(t, repo),
+	}
+
+	configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
+	require.NoError(t, err)
+	source, err := repo.CreateSource(t.Context(), "Test Source", ".")
+	require.NoError(t, err)
+	uploads, err := repo.CreateUploads(t.Context(), configuration.ID(), []id.SourceID{source.ID()})
+	require.NoError(t, err)
+	require.Len(t, uploads, 1)
+	upload := uploads[0]
+
+	nodeCid1 := testutil.RandomCID(t)
+	nodeCid2 := testutil.RandomCID(t)
+	_, _, err = repo.FindOrCreateRawNode(t.Context(), nodeCid1, 1<<15, "some/path", source.ID(), 0)
+	require.NoError(t, err)
+	_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), nodeCid1)
+	require.NoError(t, err)
+	_, _, err = repo.FindOrCreateRawNode(t.Context(), nodeCid2, 1<<15, "some/other/path", source.ID(), 0)
+	require.NoError(t, err)
+	_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), nodeCid2)
+	require.NoError(t, err)
+
+	rootCid := testutil.RandomCID(t)
+
+	// Refuses to register while any shard is yet to be added.
+	err = api.RegisterUploadShards(t.Context(), upload.ID(), rootCid)
+	require.Error(t, err)
+	require.Empty(t, uploadAdder.invocations)
+
+	_, err = api.CloseUploadShards(t.Context(), upload.ID(), nodeCid2)
+	require.NoError(t, err)
+	err = api.SpaceBlobAddShardsForUpload(t.Context(), upload.ID())
+	require.NoError(t, err)
+
+	addedShards, err := repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateAdded)
+	require.NoError(t, err)
+	require.Len(t, addedShards, 2)
+
+	err = api.RegisterUploadShards(t.Context(), upload.ID(), rootCid)
+	require.NoError(t, err)
+
+	require.Len(t, uploadAdder.invocations, 1)
+	require.Equal(t, spaceDID, uploadAdder.invocations[0].space)
+	require.Equal(t, rootCid.String(), uploadAdder.invocations[0].root.String())
+
+	expectedShardCids := make([]string, 0, len(spaceBlobAdder.invocations))
+	for _, inv := range spaceBlobAdder.invocations {
+		digest, err := multihash.Sum(inv.contentRead, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+		expectedShardCids = append(expectedShardCids, cid.NewCidV1(uint64(multicodec.Car), digest).String())
+	}
+	registeredShardCids := make([]string, 0, len(uploadAdder.invocations[0].shards))
+	for _, shard := range uploadAdder.invocations[0].shards {
+		registeredShardCids = append(registeredShardCids, shard.String())
+	}
+	require.ElementsMatch(t, expectedShardCids, registeredShardCids)
+}
+
+func TestAddIndexForUpload(t *testing.T) {
+	db := testutil.CreateTestDB(t)
+	repo := sqlrepo.New(db)
+	spaceDID, err := did.Parse("did:storacha:space:example")
+	require.NoError(t, err)
+	spaceBlobAdder := mockSpaceBlobAdder{T: t}
+	indexAdder := mockIndexAdder{}
+
+	api := shards.API{
+		Repo:        repo,
+		Client:      &spaceBlobAdder,
+		IndexAdder:  &indexAdder,
+		Space:       spaceDID,
+		CarForShard: carForShard(t, repo),
+	}
+
+	configuration, err := repo.CreateConfiguration(t.Context(), "Test Config", configurationsmodel.WithShardSize(1<<16))
+	require.NoError(t, err)
+	source, err := repo.CreateSource(t.Context(), "Test Source", ".")
+	require.NoError(t, err)
+	uploads, err := repo.CreateUploads(t.Context(), configuration.ID(), []id.SourceID{source.ID()})
+	require.NoError(t, err)
+	require.Len(t, uploads, 1)
+	upload := uploads[0]
+
+	nodeCid1 := testutil.RandomCID(t)
+	nodeCid2 := testutil.RandomCID(t)
+	_, _, err = repo.FindOrCreateRawNode(t.Context(), nodeCid1, 1<<15, "some/path", source.ID(), 0)
+	require.NoError(t, err)
+	_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), nodeCid1)
+	require.NoError(t, err)
+	_, _, err = repo.FindOrCreateRawNode(t.Context(), nodeCid2, 1<<15, "some/other/path", source.ID(), 0)
+	require.NoError(t, err)
+	_, err = api.AddNodeToUploadShards(t.Context(), upload.ID(), nodeCid2)
+	require.NoError(t, err)
+	_, err = api.CloseUploadShards(t.Context(), upload.ID(), nodeCid2)
+	require.NoError(t, err)
+
+	err = api.SpaceBlobAddShardsForUpload(t.Context(), upload.ID())
+	require.NoError(t, err)
+	require.Len(t, spaceBlobAdder.invocations, 2)
+	shardCars := map[string][]byte{}
+	for _, inv := range spaceBlobAdder.invocations {
+		digest, err := multihash.Sum(inv.contentRead, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+		shardCars[string(digest)] = inv.contentRead
+	}
+
+	rootCid := nodeCid2
+	err = api.AddIndexForUpload(t.Context(), upload.ID(), rootCid)
+	require.NoError(t, err)
+
+	// The index itself is added as a blob, then registered.
+	require.Len(t, spaceBlobAdder.invocations, 3)
+	indexBytes := spaceBlobAdder.invocations[2].contentRead
+	indexDigest, err := multihash.Sum(indexBytes, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+
+	require.Len(t, indexAdder.invocations, 1)
+	require.Equal(t, spaceDID, indexAdder.invocations[0].space)
+	require.Equal(t, cid.NewCidV1(uint64(multicodec.Car), indexDigest).String(), indexAdder.invocations[0].index.String())
+
+	index, err := blobindex.Extract(bytes.NewReader(indexBytes))
+	require.NoError(t, err)
+	require.Equal(t, rootCid.String(), index.Content().String())
+	require.Equal(t, 2, index.Shards().Size())
+
+	addedShards, err := repo.ShardsForUploadByStatus(t.Context(), upload.ID(), model.ShardStateAdded)
+	require.NoError(t, err)
+	require.Len(t, addedShards, 2)
+	for _, shard := range addedShards {
+		slices := index.Shards().Get(shard.CID().Hash())
+		require.NotNil(t, slices, "expected shard %s to be indexed", shard.CID())
+
+		var indexed int
+		for sliceDigest, position := range slices.Iterator() {
+			indexed++
+			nodeCid := cid.NewCidV1(cid.Raw, sliceDigest)
+			require.Equal(t, uint64(1<<15), position.Length)
+
+			// The block's data follows its CID in the shard's CAR.
+			car := shardCars[string(shard.CID().Hash())]
+			require.NotNil(t, car)
+			cidBytes := nodeCid.Bytes()
+			require.GreaterOrEqual(t, position.Offset, uint64(len(cidBytes)))
+			require.LessOrEqual(t, position.Offset+position.Length, uint64(len(car)))
+			require.Equal(t, cidBytes, car[position.Offset-uint64(len(cidBytes)):position.Offset])
+		}
+		require.Equal(t, 1, indexed)
+	}
+}
+
+// BenchmarkAddNodeToUploadShards measures adding a node to a shard which
+// already holds a given number of nodes. Deciding whether the node fits uses
+// the shard's running size, so the cost shouldn't grow with the shard. For
+// comparison, the "summing node sizes" cases also sum the sizes of the nodes
+// already in the shard on each add, as deciding whether the node fits used to.
+func BenchmarkAddNodeToUploadShards(b *testing.B) {
+	for _, existing := range []int{10, 100, 1000, 10000} {
+		for _, sumSizes := range []bool{false, true} {
+			name := fmt.Sprintf("%d nodes in shard, running size", existing)
+			if sumSizes {
+				name = fmt.Sprintf("%d nodes in shard, summing node sizes", existing)
+			}
+
+			b.Run(name, func(b *testing.B) {
+				db := testutil.CreateTestDB(b)
+				repo := sqlrepo.New(db)
+				api := shards.API{Repo: repo}
+
+				// Large enough that the shard never fills.
+				configuration, err := repo.CreateConfiguration(b.Context(), "Test Config", configurationsmodel.WithShardSize(configurationsmodel.MaxShardSize-1))
+				require.NoError(b, err)
+				source, err := repo.CreateSource(b.Context(), "Test Source", ".")
+				require.NoError(b, err)
+				uploads, err := repo.CreateUploads(b.Context(), configuration.ID(), []id.SourceID{source.ID()})
+				require.NoError(b, err)
+				upload := uploads[0]
+
+				addNode := func() {
+					nodeCid := testutil.RandomCID(b)
+					_, _, err := repo.FindOrCreateRawNode(b.Context(), nodeCid, 1<<10, "some/path", source.ID(), 0)
+					require.NoError(b, err)
+					_, err = api.AddNodeToUploadShards(b.Context(), upload.ID(), nodeCid)
+					require.NoError(b, err)
+				}
+
+				// Sums the sizes of the nodes in the open shard, visiting each of them.
+				sumNodeSizes := func() {
+					openShards, err := repo.ShardsForUploadByStatus(b.Context(), upload.ID(), model.ShardStateOpen)
+					require.NoError(b, err)
+					var total uint64
+					for _, shard := range openShards {
+						err := repo.ForEachNode(b.Context(), shard.ID(), func(node dagsmodel.Node) error {
+							total += encodedNodeLength(node.CID(), node.Size())
+							return nil
+						})
+						require.NoError(b, err)
+					}
+				}
+
+				for range existing {
+					addNode()
+				}
+
+				b.ResetTimer()
+				for range b.N {
+					if sumSizes {
+						sumNodeSizes()
+					}
+					addNode()
+				}
+			})
+		}
+	}
 }
@@ -285,6 +285,20 @@ func (r *repo) FindOrCreateUnixFSNode(ctx context.Context, cid cid.Cid, size uin
 	return newNode, true, nil
 }
 
+// NodeInShard reports whether the node with the given CID has been added to a
+// shard of any upload.
+func (r *repo) NodeInShard(ctx context.Context, c cid.Cid) (bool, error) {
+	var inShard bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM nodes_in_shards WHERE node_cid = ?)`,
+		c.Bytes(),
+	).Scan(&inShard)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether node %s is in a shard: %w", c, err)
+	}
+	return inShard, nil
+}
+
 // GetChildScans finds scans for child nodes of a given directory scan's file system entry.
 func (r *repo) GetChildScans(ctx context.Context, directoryScans *model.DirectoryDAGScan) ([]model.DAGScan, error) {
 	query := `SELECT fs_entry_id, upload_id, created_at, updated_at, state, error_message, cid, kind FROM dag_scans JOIN directory_children ON directory_children.child_id = dag_scans.fs_entry_id WHERE directory_children.directory_id = ?`
@@ -82,6 +82,29 @@ func TestFindOrCreateRawNode(t *testing.T) {
 	})
 }
 
+func TestNodeInShard(t *testing.T) {
+	t.Run("reports whether a node has been added to a shard", func(t *testing.T) {
+		repo := sqlrepo.New(testutil.CreateTestDB(t))
+
+		nodeCid := testutil.RandomCID(t)
+		_, _, err := repo.FindOrCreateRawNode(t.Context(), nodeCid, 16, "some/path", id.New(), 0)
+		require.NoError(t, err)
+
+		inShard, err := repo.NodeInShard(t.Context(), nodeCid)
+		require.NoError(t, err)
+		require.False(t, inShard)
+
+		shard, err := repo.CreateShard(t.Context(), id.New())
+		require.NoError(t, err)
+		err = repo.AddNodeToShard(t.Context(), shard.ID(), nodeCid, 20)
+		require.NoError(t, err)
+
+		inShard, err = repo.NodeInShard(t.Context(), nodeCid)
+		require.NoError(t, err)
+		require.True(t, inShard)
+	})
+}
+
 func TestDirectoryLinks(t *testing.T) {
 	t.Run("for a new DAG scan is empty", func(t *testing.T) {
 		repo := sqlrepo.New(testutil.CreateTestDB(t))
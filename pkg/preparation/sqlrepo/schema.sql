@@ -151,5 +151,14 @@ CREATE TABLE IF NOT EXISTS shards (
   -- If NULL, has not yet been calculated (and maybe cannot be, if still
   -- accepting new nodes)
   cid BLOB,
+  -- The root CID declared in the shard's CAR header
+  -- If NULL, the header declares no roots (only the shard holding the upload's
+  -- root node declares one)
+  root_cid BLOB,
+  -- The total encoded length of the blocks in the shard's CAR, not including
+  -- the header, kept up to date as nodes are added
+  size INTEGER NOT NULL DEFAULT 0,
+  -- The number of blocks in the shard, kept up to date as nodes are added
+  block_count INTEGER NOT NULL DEFAULT 0,
   state TEXT NOT NULL
 ) STRICT;
\ No newline at end of file
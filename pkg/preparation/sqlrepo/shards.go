@@ -2,6 +2,8 @@ package sqlrepo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/ipfs/go-cid"
@@ -20,17 +22,23 @@ func (r *repo) CreateShard(ctx context.Context, uploadID id.UploadID) (*model.Sh
 		return nil, err
 	}
 
-	err = model.WriteShardToDatabase(shard, func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, state model.ShardState) error {
+	err = model.WriteShardToDatabase(shard, func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, root cid.Cid, size uint64, blockCount uint64, state model.ShardState) error {
 		_, err := r.db.ExecContext(ctx, `
 			INSERT INTO shards (
 				id,
 				upload_id,
 				cid,
+				root_cid,
+				size,
+				block_count,
 				state
-			) VALUES (?, ?, ?, ?)`,
+			) VALUES (?, ?, ?, ?, ?, ?, ?)`,
 			id,
 			uploadID,
 			util.DbCid(&cid),
+			util.DbCid(&root),
+			size,
+			blockCount,
 			state,
 		)
 		return err
@@ -48,6 +56,9 @@ func (r *repo) ShardsForUploadByStatus(ctx context.Context, uploadID id.UploadID
 			id,
 			upload_id,
 			cid,
+			root_cid,
+			size,
+			block_count,
 			state
 		FROM shards
 		WHERE upload_id = ?
@@ -66,9 +77,12 @@ func (r *repo) ShardsForUploadByStatus(ctx context.Context, uploadID id.UploadID
 			id *id.ShardID,
 			uploadID *id.UploadID,
 			cid *cid.Cid,
+			root *cid.Cid,
+			size *uint64,
+			blockCount *uint64,
 			state *model.ShardState,
 		) error {
-			return rows.Scan(id, uploadID, util.DbCid(cid), state)
+			return rows.Scan(id, uploadID, util.DbCid(cid), util.DbCid(root), size, blockCount, state)
 		})
 		if err != nil {
 			return nil, err
@@ -81,8 +95,17 @@ func (r *repo) ShardsForUploadByStatus(ctx context.Context, uploadID id.UploadID
 	return shards, nil
 }
 
-func (r *repo) AddNodeToShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid) error {
-	_, err := r.db.ExecContext(ctx, `
+// AddNodeToShard adds the node to the shard, and adds `encodedSize` to the
+// shard's running size and one to its block count, so that the shard's size
+// is known without visiting each of its nodes.
+func (r *repo) AddNodeToShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid, encodedSize uint64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction to add node %s to shard %s: %w", nodeCID, shardID, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO nodes_in_shards (
 			node_cid,
 			shard_id
@@ -93,9 +116,68 @@ func (r *repo) AddNodeToShard(ctx context.Context, shardID id.ShardID, nodeCID c
 	if err != nil {
 		return fmt.Errorf("failed to add node %s to shard %s: %w", nodeCID, shardID, err)
 	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE shards
+		SET size = size + ?,
+		    block_count = block_count + 1
+		WHERE id = ?`,
+		encodedSize,
+		shardID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update size of shard %s: %w", shardID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit adding node %s to shard %s: %w", nodeCID, shardID, err)
+	}
 	return nil
 }
 
+// ShardForNode returns the shard of the given upload which holds the node with
+// the given CID, or nil if no shard of the upload holds it. If more than one
+// does, it returns the one the node was most recently added to.
+func (r *repo) ShardForNode(ctx context.Context, uploadID id.UploadID, nodeCID cid.Cid) (*model.Shard, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT
+			shards.id,
+			shards.upload_id,
+			shards.cid,
+			shards.root_cid,
+			shards.size,
+			shards.block_count,
+			shards.state
+		FROM nodes_in_shards
+		JOIN shards ON shards.id = nodes_in_shards.shard_id
+		WHERE shards.upload_id = ?
+		  AND nodes_in_shards.node_cid = ?
+		ORDER BY nodes_in_shards.rowid DESC
+		LIMIT 1`,
+		uploadID,
+		nodeCID.Bytes(),
+	)
+
+	shard, err := model.ReadShardFromDatabase(func(
+		id *id.ShardID,
+		uploadID *id.UploadID,
+		cid *cid.Cid,
+		root *cid.Cid,
+		size *uint64,
+		blockCount *uint64,
+		state *model.ShardState,
+	) error {
+		return row.Scan(id, uploadID, util.DbCid(cid), util.DbCid(root), size, blockCount, state)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shard for node %s in upload %s: %w", nodeCID, uploadID, err)
+	}
+	return shard, nil
+}
+
 func (r *repo) FindNodeByCid(ctx context.Context, c cid.Cid) (dagsmodel.Node, error) {
 	findQuery := `
 		SELECT
@@ -116,6 +198,8 @@ func (r *repo) FindNodeByCid(ctx context.Context, c cid.Cid) (dagsmodel.Node, er
 	return r.getNodeFromRow(row)
 }
 
+// ForEachNode calls `yield` for each node in the shard, in the order they were
+// added. The order is stable, so a shard's CAR can be produced more than once.
 func (r *repo) ForEachNode(ctx context.Context, shardID id.ShardID, yield func(dagsmodel.Node) error) error {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT
@@ -127,7 +211,8 @@ func (r *repo) ForEachNode(ctx context.Context, shardID id.ShardID, yield func(d
 			nodes.offset
 		FROM nodes_in_shards
 		JOIN nodes ON nodes.cid = nodes_in_shards.node_cid
-		WHERE shard_id = ?`,
+		WHERE shard_id = ?
+		ORDER BY nodes_in_shards.rowid`,
 		shardID,
 	)
 	if err != nil {
@@ -148,19 +233,73 @@ func (r *repo) ForEachNode(ctx context.Context, shardID id.ShardID, yield func(d
 	return nil
 }
 
-// UpdateShard updates a DAG scan in the repository.
+func (r *repo) SetNodeOffsetInShard(ctx context.Context, shardID id.ShardID, nodeCID cid.Cid, offset uint64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE nodes_in_shards
+		SET shard_offset = ?
+		WHERE shard_id = ?
+		  AND node_cid = ?`,
+		offset,
+		shardID,
+		nodeCID.Bytes(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set offset of node %s in shard %s: %w", nodeCID, shardID, err)
+	}
+	return nil
+}
+
+func (r *repo) ForEachNodePosition(ctx context.Context, shardID id.ShardID, yield func(nodeCID cid.Cid, offset uint64, length uint64) error) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			nodes.cid,
+			nodes.size,
+			nodes_in_shards.shard_offset
+		FROM nodes_in_shards
+		JOIN nodes ON nodes.cid = nodes_in_shards.node_cid
+		WHERE shard_id = ?
+		ORDER BY nodes_in_shards.rowid`,
+		shardID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get positions of blocks in shard %s: %w", shardID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nodeCID cid.Cid
+		var size uint64
+		var offset sql.NullInt64
+		if err := rows.Scan(util.DbCid(&nodeCID), &size, &offset); err != nil {
+			return fmt.Errorf("failed to scan position of block in shard %s: %w", shardID, err)
+		}
+		if !offset.Valid {
+			return fmt.Errorf("offset of node %s in shard %s has not been recorded", nodeCID, shardID)
+		}
+		if err := yield(nodeCID, uint64(offset.Int64), size); err != nil {
+			return fmt.Errorf("failed to yield position of node %s for shard %s: %w", nodeCID, shardID, err)
+		}
+	}
+
+	return rows.Err()
+}
+
+// UpdateShard updates a shard in the repository. The shard's size and block
+// count are left alone, as they're maintained by [repo.AddNodeToShard].
 func (r *repo) UpdateShard(ctx context.Context, shard *model.Shard) error {
-	return model.WriteShardToDatabase(shard, func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, state model.ShardState) error {
+	return model.WriteShardToDatabase(shard, func(id id.ShardID, uploadID id.UploadID, cid cid.Cid, root cid.Cid, size uint64, blockCount uint64, state model.ShardState) error {
 		_, err := r.db.ExecContext(ctx,
 			`UPDATE shards
 			SET id = ?,
 			    upload_id = ?,
 			    cid = ?,
+			    root_cid = ?,
 			    state = ?
 			WHERE id = ?`,
 			id,
 			uploadID,
 			util.DbCid(&cid),
+			util.DbCid(&root),
 			state,
 			id,
 		)
@@ -3,6 +3,7 @@ package testutil
 import (
 	crand "crypto/rand"
 	"database/sql"
+	"path/filepath"
 	"testing"
 
 	"github.com/ipfs/go-cid"
@@ -14,7 +15,7 @@ import (
 
 // CreateTestDB creates a temporary SQLite database for testing. It returns the
 // database connection, a cleanup function, and any error encountered.
-func CreateTestDB(t *testing.T) *sql.DB {
+func CreateTestDB(t testing.TB) *sql.DB {
 	t.Helper()
 
 	db, err := sql.Open("sqlite", "file::memory:?cache=shared")
@@ -34,7 +35,36 @@ func CreateTestDB(t *testing.T) *sql.DB {
 	return db
 }
 
-func RandomCID(t *testing.T) cid.Cid {
+// CreateTestFileDB creates a temporary SQLite database for testing in a file,
+// which, unlike the shared in-memory database, can handle concurrent writers.
+// As with [CreateTestDB], foreign key checks are disabled, here on every
+// connection in the pool.
+func CreateTestFileDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(0)")
+	require.NoError(t, err, "failed to open SQLite database")
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	// The schema enables foreign keys on the connection it runs on, so disable
+	// them again there.
+	conn, err := db.Conn(t.Context())
+	require.NoError(t, err, "failed to get connection")
+	defer conn.Close()
+
+	_, err = conn.ExecContext(t.Context(), sqlrepo.Schema)
+	require.NoError(t, err, "failed to execute schema")
+
+	_, err = conn.ExecContext(t.Context(), "PRAGMA foreign_keys = OFF;")
+	require.NoError(t, err, "failed to disable foreign keys")
+
+	return db
+}
+
+func RandomCID(t testing.TB) cid.Cid {
 	t.Helper()
 
 	bytes := make([]byte, 10)
@@ -29,6 +29,10 @@ const (
 	// sharding.
 	UploadStateDagged UploadState = "dagged"
 
+	// UploadStateSharded indicates that every shard of the upload has been
+	// added to the space, but the upload has not yet been registered.
+	UploadStateSharded UploadState = "sharded"
+
 	// UploadStateCompleted indicates that the entire upload has completed
 	// successfully.
 	UploadStateCompleted UploadState = "completed"
@@ -42,7 +46,7 @@ const (
 
 func validUploadState(state UploadState) bool {
 	switch state {
-	case UploadStatePending, UploadStateStarted, UploadStateScanned, UploadStateDagged, UploadStateCompleted, UploadStateFailed, UploadStateCanceled:
+	case UploadStatePending, UploadStateStarted, UploadStateScanned, UploadStateDagged, UploadStateSharded, UploadStateCompleted, UploadStateFailed, UploadStateCanceled:
 		return true
 	default:
 		return false
@@ -54,7 +58,7 @@ func TerminatedState(state UploadState) bool {
 }
 
 func RestartableState(state UploadState) bool {
-	return state == UploadStateStarted || state == UploadStateScanned || state == UploadStateDagged || state == UploadStateCanceled
+	return state == UploadStateStarted || state == UploadStateScanned || state == UploadStateDagged || state == UploadStateSharded || state == UploadStateCanceled
 }
 
 // Upload represents the process of full or partial upload of data from a source, eventually represented as an upload in storacha.
@@ -129,7 +133,7 @@ func (u *Upload) Fail(errorMessage string) error {
 }
 
 func (u *Upload) Complete() error {
-	if u.state != UploadStateDagged {
+	if u.state != UploadStateSharded {
 		return fmt.Errorf("cannot complete upload in state %s", u.state)
 	}
 	u.state = UploadStateCompleted
@@ -180,6 +184,16 @@ func (u *Upload) DAGGenerationComplete(rootCID cid.Cid) error {
 	return nil
 }
 
+func (u *Upload) ShardsAdded() error {
+	if u.state != UploadStateDagged {
+		return fmt.Errorf("cannot complete adding shards in state %s", u.state)
+	}
+	u.state = UploadStateSharded
+	u.errorMessage = nil
+	u.updatedAt = time.Now()
+	return nil
+}
+
 func (u *Upload) Restart() error {
 	if !RestartableState(u.state) {
 		return fmt.Errorf("cannot restart upload in state %s", u.state)
@@ -281,3 +295,7 @@ func (u *Upload) NeedsDagScan() bool {
 func (u *Upload) NeedsUpload() bool {
 	return u.state == UploadStatePending || u.state == UploadStateStarted || u.state == UploadStateScanned || u.state == UploadStateDagged
 }
+
+func (u *Upload) NeedsRegistration() bool {
+	return u.state == UploadStatePending || u.state == UploadStateStarted || u.state == UploadStateScanned || u.state == UploadStateDagged || u.state == UploadStateSharded
+}
@@ -16,11 +16,13 @@ import (
 var log = logging.Logger("preparation/uploads")
 
 type RunNewScanFunc func(ctx context.Context, uploadID id.UploadID, fsEntryCb func(id id.FSEntryID, isDirectory bool) error) (id.FSEntryID, error)
-type RunDagScansForUploadFunc func(ctx context.Context, uploadID id.UploadID, nodeCB func(node dagmodel.Node, data []byte) error) error
+type RunDagScansForUploadFunc func(ctx context.Context, uploadID id.UploadID, concurrency int, nodeCB func(node dagmodel.Node) error) error
 type RestartDagScansForUploadFunc func(ctx context.Context, uploadID id.UploadID) error
 type AddNodeToUploadShardsFunc func(ctx context.Context, uploadID id.UploadID, nodeCID cid.Cid) (bool, error)
-type CloseUploadShardsFunc func(ctx context.Context, uploadID id.UploadID) (bool, error)
+type CloseUploadShardsFunc func(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) (bool, error)
 type SpaceBlobAddShardsForUploadFunc func(ctx context.Context, uploadID id.UploadID) error
+type AddIndexForUploadFunc func(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) error
+type RegisterUploadShardsFunc func(ctx context.Context, uploadID id.UploadID, rootCID cid.Cid) error
 
 type API struct {
 	Repo                        Repo
@@ -34,10 +36,21 @@ type API struct {
 	// false otherwise.
 	AddNodeToUploadShards AddNodeToUploadShardsFunc
 
-	// CloseUploadShards closes any remaining open shard for the upload. It
-	// returns true if an existing open shard was in fact closed, false if there
-	// was no open shard to close.
+	// CloseUploadShards closes any remaining open shard for the upload. The shard
+	// holding the root node declares the root CID in its CAR header. It returns
+	// true if an existing open shard was in fact closed, false if there was no
+	// open shard to close.
 	CloseUploadShards CloseUploadShardsFunc
+
+	// AddIndexForUpload builds, stores and registers the index of the upload's
+	// blocks within its shards. It's only called once every shard has been
+	// added to the space.
+	AddIndexForUpload AddIndexForUploadFunc
+
+	// RegisterUploadShards registers the upload's root CID with the CARs of all
+	// of its shards (`upload/add`). It's only called once every shard has been
+	// added to the space.
+	RegisterUploadShards RegisterUploadShardsFunc
 }
 
 // CreateUploads creates uploads for a given configuration and its associated sources.
@@ -68,17 +81,42 @@ func (a API) GetUploadByID(ctx context.Context, uploadID id.UploadID) (*model.Up
 	return a.Repo.GetUploadByID(ctx, uploadID)
 }
 
+// ExecuteOption configures the execution of an upload.
+type ExecuteOption func(e *executor) error
+
+// WithDAGScanConcurrency sets how many DAG scans may run at once. Nodes are
+// still added to the upload's shards one at a time, in a deterministic order.
+// The default is 1.
+func WithDAGScanConcurrency(concurrency int) ExecuteOption {
+	return func(e *executor) error {
+		if concurrency < 1 {
+			return fmt.Errorf("DAG scan concurrency must be at least 1, got %d", concurrency)
+		}
+		e.dagScanConcurrency = concurrency
+		return nil
+	}
+}
+
 // ExecuteUpload executes the upload process for a given upload, handling its state transitions and processing steps.
-func (a API) ExecuteUpload(ctx context.Context, upload *model.Upload) (cid.Cid, error) {
-	return executor{
-		upload: upload,
-		api:    a,
-	}.execute(ctx)
+func (a API) ExecuteUpload(ctx context.Context, upload *model.Upload, options ...ExecuteOption) (cid.Cid, error) {
+	e := executor{
+		upload:             upload,
+		api:                a,
+		dagScanConcurrency: 1,
+	}
+	for _, opt := range options {
+		if err := opt(&e); err != nil {
+			return cid.Undef, fmt.Errorf("applying option: %w", err)
+		}
+	}
+	return e.execute(ctx)
 }
 
 type executor struct {
 	upload *model.Upload
 	api    API
+	// dagScanConcurrency is how many DAG scans may run at once.
+	dagScanConcurrency int
 }
 
 // signalWorkAvailable signals on a channel that work is available. The channel
@@ -96,7 +134,7 @@ func signalWorkAvailable(work chan<- struct{}) {
 func (e executor) execute(ctx context.Context) (cid.Cid, error) {
 	log.Debugf("Executing upload %s in state %s", e.upload.ID(), e.upload.State())
 
-	eg, ctx := errgroup.WithContext(ctx)
+	eg, workerCtx := errgroup.WithContext(ctx)
 	dagWork := make(chan struct{}, 1)
 	blobWork := make(chan struct{}, 1)
 
@@ -110,26 +148,46 @@ func (e executor) execute(ctx context.Context) (cid.Cid, error) {
 		}
 	}
 
-	// start the workers for all states not yet handled
-	if e.upload.NeedsScan() {
+	// Decide which stages are needed before starting any worker, since the
+	// workers update the upload as they go.
+	needsScan := e.upload.NeedsScan()
+	needsDagScan := e.upload.NeedsDagScan()
+	needsUpload := e.upload.NeedsUpload()
+
+	// start the workers for all states not yet handled. When resuming, a stage
+	// which has already finished won't produce work for the next one, so signal
+	// once to pick up anything left in the DB, and then close the channel.
+	if needsScan {
 		eg.Go(func() error {
-			return e.runScanWorker(ctx, dagWork)
+			return e.runScanWorker(workerCtx, dagWork)
 		})
+	} else {
+		signalWorkAvailable(dagWork)
+		close(dagWork)
 	}
-	if e.upload.NeedsDagScan() {
+	if needsDagScan {
 		eg.Go(func() error {
-			return e.runDAGScanWorker(ctx, dagWork, blobWork)
+			return e.runDAGScanWorker(workerCtx, dagWork, blobWork)
 		})
+	} else {
+		signalWorkAvailable(blobWork)
+		close(blobWork)
 	}
-	if e.upload.NeedsUpload() {
+	if needsUpload {
 		eg.Go(func() error {
-			return e.runSpaceBlobAddWorker(ctx, blobWork)
+			return e.runSpaceBlobAddWorker(workerCtx, blobWork)
 		})
 	}
 
 	log.Debugf("Waiting for workers to finish for upload %s", e.upload.ID())
 	err := eg.Wait()
 
+	// Once every shard is added, the upload can be registered. This happens
+	// after the workers are done, since it needs all of the shards.
+	if err == nil && e.upload.NeedsRegistration() {
+		err = e.register(ctx)
+	}
+
 	if errors.Is(err, context.Canceled) {
 		log.Debugf("Upload %s was canceled", e.upload.ID())
 		if err := e.upload.Cancel(); err != nil {
@@ -177,7 +235,6 @@ func (e *executor) runScanWorker(ctx context.Context, dagWork chan<- struct{}) e
 	}
 
 	log.Debugf("Scan completed successfully, root fs entry ID: %s", fsEntryID)
-	close(dagWork) // close the work channel to signal completion
 
 	if err := e.upload.ScanComplete(fsEntryID); err != nil {
 		return fmt.Errorf("completing scan: %w", err)
@@ -186,6 +243,8 @@ func (e *executor) runScanWorker(ctx context.Context, dagWork chan<- struct{}) e
 		return fmt.Errorf("updating upload: %w", err)
 	}
 
+	close(dagWork) // close the work channel to signal completion
+
 	return nil
 }
 
@@ -203,7 +262,7 @@ func (e *executor) runDAGScanWorker(ctx context.Context, dagWork <-chan struct{}
 
 		// doWork
 		func() error {
-			err := e.api.RunDagScansForUpload(ctx, e.upload.ID(), func(node dagmodel.Node, data []byte) error {
+			err := e.api.RunDagScansForUpload(ctx, e.upload.ID(), e.dagScanConcurrency, func(node dagmodel.Node) error {
 				log.Debugf("Adding node %s to upload shards for upload %s", node.CID(), e.upload.ID())
 				shardClosed, err := e.api.AddNodeToUploadShards(ctx, e.upload.ID(), node.CID())
 				if err != nil {
@@ -240,7 +299,7 @@ func (e *executor) runDAGScanWorker(ctx context.Context, dagWork <-chan struct{}
 			}
 
 			// We're out of nodes, so we can close any open shards for this upload.
-			shardClosed, err := e.api.CloseUploadShards(ctx, e.upload.ID())
+			shardClosed, err := e.api.CloseUploadShards(ctx, e.upload.ID(), rootCid)
 			if err != nil {
 				return fmt.Errorf("closing upload shards for upload %s: %w", e.upload.ID(), err)
 			}
@@ -249,8 +308,8 @@ func (e *executor) runDAGScanWorker(ctx context.Context, dagWork <-chan struct{}
 				signalWorkAvailable(blobWork)
 			}
 
-			close(blobWork) // close the work channel to signal completion
-
+			// Record completion before signaling it, so that the blob worker sees
+			// the upload as dagged when it finalizes.
 			if err := e.upload.DAGGenerationComplete(rootCid); err != nil {
 				return fmt.Errorf("completing DAG generation: %w", err)
 			}
@@ -258,13 +317,14 @@ func (e *executor) runDAGScanWorker(ctx context.Context, dagWork <-chan struct{}
 				return fmt.Errorf("updating upload: %w", err)
 			}
 
+			close(blobWork) // close the work channel to signal completion
+
 			return nil
 		},
 	)
 }
 
-// runSpaceBlobAddWorker runs the worker that scans files and directories into blocks,
-// and buckets them into shards.
+// runSpaceBlobAddWorker runs the worker that adds closed shards to the space.
 func (e *executor) runSpaceBlobAddWorker(ctx context.Context, blobWork <-chan struct{}) error {
 	return Worker(
 		ctx,
@@ -282,6 +342,38 @@ func (e *executor) runSpaceBlobAddWorker(ctx context.Context, blobWork <-chan st
 		},
 
 		// finalize
-		nil,
+		func() error {
+			if err := e.upload.ShardsAdded(); err != nil {
+				return fmt.Errorf("completing adding shards: %w", err)
+			}
+			if err := e.api.Repo.UpdateUpload(ctx, e.upload); err != nil {
+				return fmt.Errorf("updating upload: %w", err)
+			}
+
+			return nil
+		},
 	)
 }
+
+// register indexes the upload and registers it and its shards with the
+// service, completing the upload.
+func (e *executor) register(ctx context.Context) error {
+	log.Debugf("Registering upload %s with root %s", e.upload.ID(), e.upload.RootCID())
+
+	if err := e.api.AddIndexForUpload(ctx, e.upload.ID(), e.upload.RootCID()); err != nil {
+		return fmt.Errorf("adding index for upload %s: %w", e.upload.ID(), err)
+	}
+
+	if err := e.api.RegisterUploadShards(ctx, e.upload.ID(), e.upload.RootCID()); err != nil {
+		return fmt.Errorf("registering upload %s: %w", e.upload.ID(), err)
+	}
+
+	if err := e.upload.Complete(); err != nil {
+		return fmt.Errorf("completing upload: %w", err)
+	}
+	if err := e.api.Repo.UpdateUpload(ctx, e.upload); err != nil {
+		return fmt.Errorf("updating upload: %w", err)
+	}
+
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/storacha/go-ucanto/transport/car"
 	ucanhttp "github.com/storacha/go-ucanto/transport/http"
 	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/guppy/pkg/retry"
 )
 
 var ErrNotFound = errors.New("receipt not found")
@@ -59,7 +60,8 @@ func New(endpoint *url.URL, options ...Option) *Client {
 }
 
 // Fetch a receipt from the receipt API. Returns [ErrNotFound] if the API
-// responds with [http.StatusNotFound].
+// responds with [http.StatusNotFound], and a [retry.StatusError] for any other
+// unsuccessful status.
 func (c *Client) Fetch(ctx context.Context, task ucan.Link) (receipt.AnyReceipt, error) {
 	receiptURL := c.endpoint.JoinPath(task.String())
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, receiptURL.String(), nil)
@@ -83,7 +85,7 @@ func (c *Client) Fetch(ctx context.Context, task ucan.Link) (receipt.AnyReceipt,
 	case http.StatusNotFound:
 		return nil, ErrNotFound
 	default:
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, retry.NewStatusError(resp)
 	}
 
 	rcptlnk, ok := msg.Get(task)
@@ -98,6 +100,7 @@ func (c *Client) Fetch(ctx context.Context, task ucan.Link) (receipt.AnyReceipt,
 type pollConfig struct {
 	interval *time.Duration
 	retries  *int
+	policy   *retry.Policy
 }
 
 type PollOption func(opt *pollConfig)
@@ -119,6 +122,17 @@ func WithRetries(n int) PollOption {
 	}
 }
 
+// WithRetryPolicy configures Poll to retry transient failures to fetch the
+// receipt, such as network errors and 5xx responses, under the given policy.
+// The policy doesn't affect requests which find no receipt, which are still
+// made at the configured interval. By default, any failure other than
+// [ErrNotFound] ends polling.
+func WithRetryPolicy(policy retry.Policy) PollOption {
+	return func(opt *pollConfig) {
+		opt.policy = &policy
+	}
+}
+
 // Poll attempts to fetch a receipt from the endpoint until a non-404 response
 // is encountered or until the configured maximum retries are made.
 func (c *Client) Poll(ctx context.Context, task ucan.Link, options ...PollOption) (receipt.AnyReceipt, error) {
@@ -134,26 +148,38 @@ func (c *Client) Poll(ctx context.Context, task ucan.Link, options ...PollOption
 	}
 
 	attempts := 0
+	failures := 0
 	for {
+		var wait time.Duration
 		rcpt, err := c.Fetch(ctx, task)
-		if err != nil && !errors.Is(err, ErrNotFound) {
-			return nil, err
-		}
-		if err == nil {
+		switch {
+		case err == nil:
 			return rcpt, nil
-		}
 
-		attempts++
-		if *conf.retries > -1 && (attempts-1) >= *conf.retries {
-			return nil, fmt.Errorf("receipt was not found after %d attempts", attempts)
+		case errors.Is(err, ErrNotFound):
+			attempts++
+			if *conf.retries > -1 && (attempts-1) >= *conf.retries {
+				return nil, fmt.Errorf("receipt was not found after %d attempts", attempts)
+			}
+			wait = *conf.interval
+
+		default:
+			if conf.policy == nil || !retry.Retryable(err) {
+				return nil, err
+			}
+			failures++
+			if failures >= max(conf.policy.MaxAttempts, 1) {
+				return nil, fmt.Errorf("fetching receipt failed after %d attempts: %w", failures, err)
+			}
+			wait = conf.policy.Backoff(failures)
+			if after, ok := retry.RetryAfter(err); ok && after > wait {
+				wait = after
+			}
 		}
 
-		// wait for the configured interval, or the context to be canceled
-		sleep, cancel := context.WithTimeout(ctx, *conf.interval)
-		<-sleep.Done()
-		cancel()
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+		// wait before the next attempt, or for the context to be canceled
+		if err := retry.Sleep(ctx, wait); err != nil {
+			return nil, err
 		}
 	}
 }
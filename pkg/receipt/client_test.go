@@ -20,6 +20,7 @@ import (
 	"github.com/storacha/go-ucanto/transport/car/response"
 	"github.com/storacha/go-ucanto/ucan"
 	receiptclient "github.com/storacha/guppy/pkg/receipt"
+	"github.com/storacha/guppy/pkg/retry"
 	"github.com/stretchr/testify/require"
 )
 
@@ -212,4 +213,104 @@ func TestPoll(t *testing.T) {
 		require.ErrorContains(t, err, "context canceled")
 		require.Greater(t, n, 0)
 	})
+
+	t.Run("retries transient failures under a retry policy", func(t *testing.T) {
+		n := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			if n == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			msg, err := message.Build(nil, []receipt.AnyReceipt{rcpt})
+			require.NoError(t, err)
+			res, err := response.Encode(msg)
+			require.NoError(t, err)
+			_, err = io.Copy(w, res.Body())
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		endpoint, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		client := receiptclient.New(endpoint)
+		result, err := client.Poll(
+			t.Context(),
+			inv.Link(),
+			receiptclient.WithRetryPolicy(retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, inv.Link(), result.Ran().Link())
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("polls at the default interval under a retry policy", func(t *testing.T) {
+		n := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		endpoint, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		interval := 20 * time.Millisecond
+		defer func(d time.Duration) { receiptclient.PollInterval = d }(receiptclient.PollInterval)
+		receiptclient.PollInterval = interval
+
+		client := receiptclient.New(endpoint)
+		start := time.Now()
+		_, err = client.Poll(
+			t.Context(),
+			inv.Link(),
+			receiptclient.WithRetries(2),
+			receiptclient.WithRetryPolicy(retry.NoRetry),
+		)
+		require.ErrorContains(t, err, "receipt was not found after 3 attempts")
+		require.Equal(t, 3, n)
+		require.GreaterOrEqual(t, time.Since(start), 2*interval)
+	})
+
+	t.Run("fails on transient failures without a retry policy", func(t *testing.T) {
+		n := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		endpoint, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		client := receiptclient.New(endpoint)
+		_, err = client.Poll(t.Context(), inv.Link(), receiptclient.WithInterval(time.Millisecond))
+		require.ErrorContains(t, err, "503")
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("gives up on transient failures after the policy's attempts", func(t *testing.T) {
+		n := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		endpoint, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		client := receiptclient.New(endpoint)
+		_, err = client.Poll(
+			t.Context(),
+			inv.Link(),
+			receiptclient.WithRetryPolicy(retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		)
+		require.ErrorContains(t, err, "fetching receipt failed after 3 attempts")
+		var statusErr retry.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		require.Equal(t, 3, n)
+	})
 }
@@ -0,0 +1,235 @@
+// Package retry retries operations which fail transiently, backing off
+// exponentially with jitter between attempts.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/storacha/go-ucanto/transport"
+)
+
+// Policy describes how many times to attempt an operation, and how long to
+// wait between attempts.
+type Policy struct {
+	// MaxAttempts is the most times an operation is attempted, including the
+	// first. Values below 1 mean a single attempt.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait before any retry.
+	MaxBackoff time.Duration
+	// Multiplier is the factor each wait grows by over the last. Values below 1
+	// mean 2.
+	Multiplier float64
+	// Jitter is the fraction of each wait which is randomized, from 0 (none) to
+	// 1 (anywhere from nothing to the full wait).
+	Jitter float64
+}
+
+// DefaultPolicy is the policy used when none is configured.
+var DefaultPolicy = Policy{
+	MaxAttempts:    5,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// NoRetry is a policy which attempts each operation only once.
+var NoRetry = Policy{MaxAttempts: 1}
+
+// Backoff returns the wait before the retry which follows the given number of
+// failed attempts.
+func (p Policy) Backoff(failures int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for range failures - 1 {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	backoff -= backoff * jitter * rand.Float64()
+	return time.Duration(backoff)
+}
+
+func (p Policy) maxAttempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// Do calls `op` until it succeeds, it fails with an error which isn't
+// [Retryable], the policy's attempts run out, or the context is done. It
+// returns the last error `op` returned.
+func Do(ctx context.Context, p Policy, op func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.maxAttempts() || !Retryable(err) {
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		if after, ok := RetryAfter(err); ok && after > wait {
+			wait = after
+		}
+
+		if sleepErr := Sleep(ctx, wait); sleepErr != nil {
+			return fmt.Errorf("%w (abandoned retrying: %w)", err, sleepErr)
+		}
+	}
+}
+
+// Sleep waits for the given duration, or until the context is done, in which
+// case it returns the context's error.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanentError marks an error as not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying, whatever it wraps.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// StatusError is an unsuccessful HTTP response.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is the wait the server asked for with a Retry-After header, or
+	// zero if it didn't ask for one.
+	RetryAfter time.Duration
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// NewStatusError returns a [StatusError] for the response.
+func NewStatusError(resp *http.Response) StatusError {
+	return StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header),
+	}
+}
+
+// retryableStatus reports whether an HTTP status is worth retrying: a timeout,
+// rate limiting, or a server error which may be temporary.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retryable reports whether an error is transient, and so worth retrying:
+// network errors, timeouts, and HTTP responses asking to be retried. Anything
+// else, including errors marked [Permanent] and context cancellation, is
+// permanent. Failures reported by the service in a receipt, such as
+// authorization failures, never reach here as errors to retry.
+func Retryable(err error) bool {
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode)
+	}
+	var httpErr transport.HTTPError
+	if errors.As(err, &httpErr) {
+		return retryableStatus(httpErr.Status())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// RetryAfter returns the wait a server asked for before retrying, if the
+// error is an HTTP response with a Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var statusErr StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	var httpErr transport.HTTPError
+	if errors.As(err, &httpErr) {
+		if after := parseRetryAfter(httpErr.Headers()); after > 0 {
+			return after, true
+		}
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an
+// HTTP date. It returns zero if there's no usable header.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	ucanhttp "github.com/storacha/go-ucanto/transport/http"
+	"github.com/storacha/guppy/pkg/retry"
+	"github.com/stretchr/testify/require"
+)
+
+var fastPolicy = retry.Policy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+}
+
+func TestDo(t *testing.T) {
+	t.Run("retries transient failures until success", func(t *testing.T) {
+		attempts := 0
+		err := retry.Do(t.Context(), fastPolicy, func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives up after the maximum attempts", func(t *testing.T) {
+		attempts := 0
+		err := retry.Do(t.Context(), fastPolicy, func(ctx context.Context) error {
+			attempts++
+			return retry.StatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+		})
+		require.ErrorContains(t, err, "503")
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("doesn't retry permanent failures", func(t *testing.T) {
+		attempts := 0
+		err := retry.Do(t.Context(), fastPolicy, func(ctx context.Context) error {
+			attempts++
+			return retry.Permanent(io.ErrUnexpectedEOF)
+		})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("doesn't retry with NoRetry", func(t *testing.T) {
+		attempts := 0
+		err := retry.Do(t.Context(), retry.NoRetry, func(ctx context.Context) error {
+			attempts++
+			return io.ErrUnexpectedEOF
+		})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		attempts := 0
+		err := retry.Do(ctx, retry.Policy{MaxAttempts: 10, InitialBackoff: time.Hour}, func(ctx context.Context) error {
+			attempts++
+			cancel()
+			return io.ErrUnexpectedEOF
+		})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("waits as long as Retry-After asks", func(t *testing.T) {
+		attempts := 0
+		start := time.Now()
+		err := retry.Do(t.Context(), fastPolicy, func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				return retry.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 100 * time.Millisecond}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"503", retry.StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"429", retry.StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"401", retry.StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"404", retry.StatusError{StatusCode: http.StatusNotFound}, false},
+		{"ucanto HTTP 502", fmt.Errorf("sending message: %w", ucanhttp.NewHTTPError("failed", http.StatusBadGateway, http.Header{})), true},
+		{"ucanto HTTP 400", fmt.Errorf("sending message: %w", ucanhttp.NewHTTPError("failed", http.StatusBadRequest, http.Header{})), false},
+		{"permanent", retry.Permanent(io.ErrUnexpectedEOF), false},
+		{"anything else", errors.New("unauthorized"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.retryable, retry.Retryable(tc.err))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("in seconds", func(t *testing.T) {
+		err := ucanhttp.NewHTTPError("failed", http.StatusServiceUnavailable, http.Header{"Retry-After": []string{"7"}})
+		after, ok := retry.RetryAfter(fmt.Errorf("sending message: %w", err))
+		require.True(t, ok)
+		require.Equal(t, 7*time.Second, after)
+	})
+
+	t.Run("as a date", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}},
+		}
+		after, ok := retry.RetryAfter(retry.NewStatusError(resp))
+		require.True(t, ok)
+		require.InDelta(t, time.Minute, after, float64(2*time.Second))
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		_, ok := retry.RetryAfter(retry.StatusError{StatusCode: http.StatusServiceUnavailable})
+		require.False(t, ok)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	policy := retry.Policy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	require.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	require.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	require.Equal(t, 400*time.Millisecond, policy.Backoff(3))
+	require.Equal(t, time.Second, policy.Backoff(10))
+
+	policy.Jitter = 0.5
+	for range 100 {
+		backoff := policy.Backoff(2)
+		require.GreaterOrEqual(t, backoff, 100*time.Millisecond)
+		require.LessOrEqual(t, backoff, 200*time.Millisecond)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/storacha/guppy/internal/config"
+	"github.com/storacha/guppy/internal/profile"
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:  "profile",
+		Usage: "Manage profiles, each of which has its own agent and service settings.",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List profiles, marking the current one.",
+				Action:  profileLs,
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a profile. Its service settings can be given here, or later with `guppy config set`.",
+				UsageText: "profile create <name> [--service-url <url>] [--service-did <did>] [--receipts-url <url>] [--use]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "service-url",
+						Value: "",
+						Usage: "URL of the service to use.",
+					},
+					&cli.StringFlag{
+						Name:  "service-did",
+						Value: "",
+						Usage: "DID of the service to use.",
+					},
+					&cli.StringFlag{
+						Name:  "receipts-url",
+						Value: "",
+						Usage: "URL to fetch receipts from.",
+					},
+					&cli.BoolFlag{
+						Name:  "use",
+						Value: false,
+						Usage: "Also make the profile the current one.",
+					},
+				},
+				Action: profileCreate,
+			},
+			{
+				Name:      "use",
+				Usage:     "Make a profile the current one, which is used when no --profile is given.",
+				UsageText: "profile use <name>",
+				Action:    profileUse,
+			},
+			{
+				Name:      "rm",
+				Aliases:   []string{"remove"},
+				Usage:     "Remove a profile, including its agent's private key and delegations.",
+				UsageText: "profile rm <name> [--force]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Value: false,
+						Usage: "Remove the profile even though it holds agent data, which can't be recovered.",
+					},
+				},
+				Action: profileRm,
+			},
+		},
+	})
+}
+
+func profileLs(cCtx *cli.Context) error {
+	store := cmdutil.MustGetProfileStore()
+
+	profiles, err := store.List()
+	if err != nil {
+		return fmt.Errorf("listing profiles: %w", err)
+	}
+	current, err := store.Current()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range profiles {
+		marker := " "
+		if p.Name == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, p.Name)
+		for _, setting := range []string{"service-url", "service-did", "receipts-url"} {
+			if v, _ := p.Config.Get(setting); v != "" {
+				fmt.Printf("    %s: %s\n", setting, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+func profileCreate(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("profile name is required")
+	}
+
+	store := cmdutil.MustGetProfileStore()
+
+	var cfg config.Config
+	for _, setting := range []string{"service-url", "service-did", "receipts-url"} {
+		if err := cfg.Set(setting, cCtx.String(setting)); err != nil {
+			return err
+		}
+	}
+
+	p, err := store.Create(profile.Profile{
+		Name:   cCtx.Args().First(),
+		Config: cfg,
+	})
+	if err != nil {
+		if errors.Is(err, profile.ErrExists) {
+			return fmt.Errorf("creating profile: %w; change its settings with `guppy config set` instead", err)
+		}
+		return fmt.Errorf("creating profile: %w", err)
+	}
+	fmt.Printf("Created profile %s\n", p.Name)
+
+	if cCtx.Bool("use") {
+		if err := store.Use(p.Name); err != nil {
+			return fmt.Errorf("using profile: %w", err)
+		}
+		fmt.Printf("Using profile %s\n", p.Name)
+	}
+
+	return nil
+}
+
+func profileUse(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("profile name is required")
+	}
+	name := cCtx.Args().First()
+
+	if err := cmdutil.MustGetProfileStore().Use(name); err != nil {
+		return fmt.Errorf("using profile: %w", err)
+	}
+	fmt.Printf("Using profile %s\n", name)
+
+	return nil
+}
+
+func profileRm(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("profile name is required")
+	}
+	name := cCtx.Args().First()
+
+	store := cmdutil.MustGetProfileStore()
+
+	p, err := store.Get(name)
+	if err != nil {
+		return fmt.Errorf("removing profile: %w", err)
+	}
+	if !cCtx.Bool("force") {
+		if _, err := os.Stat(p.DataPath()); err == nil {
+			return fmt.Errorf("profile %s holds agent data, which would be lost; pass --force to remove it anyway", name)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("checking profile data: %w", err)
+		}
+	}
+
+	if err := store.Remove(name); err != nil {
+		return fmt.Errorf("removing profile: %w", err)
+	}
+	fmt.Printf("Removed profile %s\n", name)
+
+	return nil
+}
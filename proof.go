@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/guppy/internal/cmdutil"
+	cdg "github.com/storacha/guppy/pkg/delegation"
+	"github.com/urfave/cli/v2"
+)
+
+func proofFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Value: false,
+			Usage: "Output the delegations as JSON. Defaults to the configured output format.",
+		},
+	}
+}
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:  "proof",
+		Usage: "Inspect delegations.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "inspect",
+				Usage:     "Show what the delegation in a proof file grants, and the chain of proofs it is based on.",
+				UsageText: "proof inspect <file>",
+				Flags:     proofFlags(),
+				Action:    proofInspect,
+			},
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "Show what the delegations this agent holds grant, and the chains of proofs they are based on.",
+				Flags:   proofFlags(),
+				Action:  proofLs,
+			},
+			{
+				Name:      "check",
+				Usage:     "Check locally whether this agent's proofs authorize it to invoke abilities on a space, as the service would.",
+				UsageText: "proof check --can <ability> [--can <ability>...] [--space <space>] [--proof <file>]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "can",
+						Aliases:  []string{"c"},
+						Usage:    "Ability to check, such as upload/add. May be given more than once.",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "space",
+						Value: "",
+						Usage: "Name or DID of space to check abilities on. Defaults to the configured space, or else the current space.",
+					},
+					&cli.StringFlag{
+						Name:  "proof",
+						Value: "",
+						Usage: "Path to file containing UCAN proof(s) to check along with the stored ones.",
+					},
+				},
+				Action: proofCheck,
+			},
+			{
+				Name:   "prune",
+				Usage:  "Remove expired and duplicate delegations from this agent's store.",
+				Action: proofPrune,
+			},
+			{
+				Name:      "revoke",
+				Usage:     "Revoke a delegation this agent issued, such as one created with `guppy delegate`, given as a proof file or as the CID of a delegation it issued.",
+				UsageText: "proof revoke <cid|file>",
+				Action:    proofRevoke,
+			},
+		},
+	})
+}
+
+func proofInspect(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("proof file is required")
+	}
+
+	proof := cmdutil.MustGetProof(cCtx.Args().First())
+
+	return printProofs(cCtx, []delegation.Delegation{proof})
+}
+
+func proofLs(cCtx *cli.Context) error {
+	c := cmdutil.MustGetClient()
+
+	return printProofs(cCtx, c.Proofs())
+}
+
+func proofCheck(cCtx *cli.Context) error {
+	proofs := []delegation.Delegation{}
+	if cCtx.String("proof") != "" {
+		proof := cmdutil.MustGetProof(cCtx.String("proof"))
+		proofs = append(proofs, proof)
+	}
+
+	c := cmdutil.MustGetClient(proofs...)
+	space := cmdutil.MustGetSpace(c, cCtx.String("space"))
+
+	failed := 0
+	for _, ability := range cCtx.StringSlice("can") {
+		if err := c.CheckAccess(cCtx.Context, ability, space.String()); err != nil {
+			failed++
+			fmt.Printf("✘ %s on %s\n", ability, space)
+			fmt.Printf("  %s\n", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+			continue
+		}
+		fmt.Printf("✔ %s on %s\n", ability, space)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d abilities are not authorized", failed, len(cCtx.StringSlice("can")))
+	}
+	return nil
+}
+
+func proofPrune(cCtx *cli.Context) error {
+	c := cmdutil.MustGetClient()
+
+	pruned, err := c.PruneProofs()
+	if err != nil {
+		return fmt.Errorf("pruning delegations: %w", err)
+	}
+
+	for _, del := range pruned {
+		if exp := del.Expiration(); exp != nil && time.Now().Unix() >= int64(*exp) {
+			fmt.Printf("Removed %s (expired %s)\n", del.Link(), time.Unix(int64(*exp), 0).Format(time.RFC3339))
+		} else {
+			fmt.Printf("Removed %s (duplicate)\n", del.Link())
+		}
+	}
+	fmt.Printf("Removed %d delegations, %d remain\n", len(pruned), len(c.Proofs()))
+
+	return nil
+}
+
+func proofRevoke(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("delegation CID or proof file is required")
+	}
+	arg := cCtx.Args().First()
+
+	c := cmdutil.MustGetClient()
+
+	var del delegation.Delegation
+	if _, err := os.Stat(arg); err == nil {
+		del = cmdutil.MustGetProof(arg)
+	} else {
+		link := cmdutil.MustParseCID(arg)
+		i := slices.IndexFunc(c.Issued(), func(d delegation.Delegation) bool { return d.Link().String() == link.String() })
+		if i < 0 {
+			return fmt.Errorf("delegation %s is not one this agent issued, pass its proof file instead", link)
+		}
+		del = c.Issued()[i]
+	}
+
+	if err := c.Revoke(cCtx.Context, del); err != nil {
+		return fmt.Errorf("revoking delegation: %w", err)
+	}
+
+	fmt.Printf("Revoked %s\n", del.Link())
+
+	return nil
+}
+
+func printProofs(cCtx *cli.Context, dels []delegation.Delegation) error {
+	now := time.Now()
+	infos := make([]cdg.Info, 0, len(dels))
+	for _, del := range dels {
+		info, err := cdg.Inspect(del, now)
+		if err != nil {
+			return fmt.Errorf("inspecting delegation %s: %w", del.Link(), err)
+		}
+		infos = append(infos, info)
+	}
+
+	if cmdutil.JSONOutput(cCtx) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+		printProofTree(info, "", "")
+	}
+	return nil
+}
+
+// printProofTree prints a delegation and its proofs as a tree. `first` prefixes
+// the delegation's first line, and `rest` the lines after it.
+func printProofTree(info cdg.Info, first string, rest string) {
+	if info.Missing {
+		fmt.Printf("%s%s (not included)\n", first, info.CID)
+		return
+	}
+
+	var flags []string
+	if info.Expired {
+		flags = append(flags, "EXPIRED")
+	}
+	if info.NotYetValid {
+		flags = append(flags, "NOT YET VALID")
+	}
+	if len(flags) > 0 {
+		fmt.Printf("%s%s [%s]\n", first, info.CID, strings.Join(flags, ", "))
+	} else {
+		fmt.Printf("%s%s\n", first, info.CID)
+	}
+
+	fmt.Printf("%s  Issuer:     %s\n", rest, info.Issuer)
+	fmt.Printf("%s  Audience:   %s\n", rest, info.Audience)
+	if info.Expiration != nil {
+		fmt.Printf("%s  Expires:    %s\n", rest, info.Expiration.Local().Format(time.RFC3339))
+	} else {
+		fmt.Printf("%s  Expires:    never\n", rest)
+	}
+	if info.NotBefore != nil {
+		fmt.Printf("%s  Not before: %s\n", rest, info.NotBefore.Local().Format(time.RFC3339))
+	}
+	fmt.Printf("%s  Capabilities:\n", rest)
+	for _, cap := range info.Capabilities {
+		if cap.Nb != nil {
+			fmt.Printf("%s    %s on %s %v\n", rest, cap.Can, cap.With, cap.Nb)
+		} else {
+			fmt.Printf("%s    %s on %s\n", rest, cap.Can, cap.With)
+		}
+	}
+
+	for i, proof := range info.Proofs {
+		if i == len(info.Proofs)-1 {
+			printProofTree(proof, rest+"└─ ", rest+"   ")
+		} else {
+			printProofTree(proof, rest+"├─ ", rest+"│  ")
+		}
+	}
+}
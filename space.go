@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	ed25519 "github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/guppy/internal/cmdutil"
+	"github.com/storacha/guppy/pkg/didmailto"
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands = append(commands, &cli.Command{
+		Name:  "space",
+		Usage: "Create and manage spaces.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Create a new space, delegating access to it to this agent.",
+				UsageText: "space create <name>",
+				Action:    spaceCreate,
+			},
+			{
+				Name:      "provision",
+				Usage:     "Provision a space with the service on behalf of an account, so that it can store data.",
+				UsageText: "space provision <space> --account <email>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "account",
+						Value:    "",
+						Usage:    "Email address of the account to provision the space with. This agent must be logged in as the account.",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "provider",
+						Value: "",
+						Usage: "DID of the storage provider. Defaults to the service's DID.",
+					},
+				},
+				Action: spaceProvision,
+			},
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List the spaces this agent has access to.",
+				Action:  spaceLs,
+			},
+			{
+				Name:      "use",
+				Usage:     "Make a space the current space, which commands act on when no --space or configured space is given.",
+				UsageText: "space use <name|did> [--name <name>]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "name",
+						Value: "",
+						Usage: "Also give the space a name to refer to it by.",
+					},
+				},
+				Action: spaceUse,
+			},
+		},
+	})
+}
+
+func spaceCreate(cCtx *cli.Context) error {
+	name := cCtx.Args().First()
+	if name == "" {
+		return fmt.Errorf("space name is required")
+	}
+
+	c := cmdutil.MustGetClient()
+
+	space, _, err := c.CreateSpace(name)
+	if err != nil {
+		return fmt.Errorf("creating space: %w", err)
+	}
+
+	key, err := ed25519.Format(space)
+	if err != nil {
+		return fmt.Errorf("formatting space key: %w", err)
+	}
+
+	fmt.Printf("Created space %s (%s)\n", name, space.DID())
+	fmt.Println()
+	fmt.Println("This is the space's private key. It is not stored anywhere. Keep it somewhere safe to recover access to the space:")
+	fmt.Println()
+	fmt.Printf("    %s\n", key)
+	fmt.Println()
+	fmt.Printf("Provision the space before using it with `guppy space provision %s --account <email>`.\n", space.DID())
+
+	return nil
+}
+
+func spaceProvision(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("space is required")
+	}
+
+	c := cmdutil.MustGetClient()
+
+	space, err := c.ResolveSpace(cCtx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	account, err := didmailto.FromEmail(cCtx.String("account"))
+	if err != nil {
+		return fmt.Errorf("invalid email address: %w", err)
+	}
+
+	provider := c.Connection().ID().DID()
+	if cCtx.String("provider") != "" {
+		provider = cmdutil.MustParseDID(cCtx.String("provider"))
+	}
+
+	if err := c.ProviderAdd(cCtx.Context, account, provider, space); err != nil {
+		return fmt.Errorf("provisioning space: %w", err)
+	}
+
+	fmt.Printf("Provisioned space %s with %s for %s\n", space, provider, cCtx.String("account"))
+
+	return nil
+}
+
+func spaceLs(cCtx *cli.Context) error {
+	c := cmdutil.MustGetClient()
+	current, _ := c.CurrentSpace()
+
+	for _, s := range c.Spaces() {
+		marker := " "
+		if s.DID == current {
+			marker = "*"
+		}
+		if s.Name == "" {
+			fmt.Printf("%s %s\n", marker, s.DID)
+		} else {
+			fmt.Printf("%s %s\t%s\n", marker, s.DID, s.Name)
+		}
+	}
+
+	return nil
+}
+
+func spaceUse(cCtx *cli.Context) error {
+	if !cCtx.Args().Present() {
+		return fmt.Errorf("space is required")
+	}
+
+	c := cmdutil.MustGetClient()
+
+	space, err := c.ResolveSpace(cCtx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if name := cCtx.String("name"); name != "" {
+		if err := c.NameSpace(name, space); err != nil {
+			return fmt.Errorf("naming space: %w", err)
+		}
+	}
+
+	if err := c.UseSpace(space); err != nil {
+		return fmt.Errorf("using space: %w", err)
+	}
+
+	fmt.Printf("Now using space %s\n", space)
+
+	// A space set in the environment or the config file takes precedence over
+	// the current space, so commands won't act on this one until it's unset.
+	if env := os.Getenv("GUPPY_SPACE"); env != "" {
+		fmt.Fprintf(os.Stderr, "Warning: GUPPY_SPACE is set to %s, which commands use instead; unset it to use this space\n", env)
+	} else if configured := cmdutil.MustGetProfile().Config.Space; configured != "" {
+		fmt.Fprintf(os.Stderr, "Warning: the configured space %s is used instead; run `guppy config set space \"\"` to use this space\n", configured)
+	}
+
+	return nil
+}